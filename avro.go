@@ -138,6 +138,7 @@ func NewCodec(schemaSpecification string) (*Codec, error) {
 		Codec:           *o,
 		Namespace:       namespace,
 		TypeNameEncoder: DefaultTypeNameEncoder,
+		TagName:         "avro",
 	}, nil
 }
 
@@ -0,0 +1,59 @@
+package avro
+
+// AvroSchema is a parsed avro schema, tying together its JSON form, its canonical form, its fingerprint
+// and its compatibility with other schemas.
+// It is named so to not be mistaken for Schema, a schema as stored by the schema registry.
+type AvroSchema struct {
+	json string
+	tree TypedSchema
+}
+
+// NewAvroSchema parses the JSON avro schema.
+func NewAvroSchema(schema string) (AvroSchema, error) {
+	tree, err := ParseSchema(schema)
+	if err != nil {
+		return AvroSchema{}, err
+	}
+
+	return AvroSchema{json: schema, tree: tree}, nil
+}
+
+// InferSchemaValue infers the avro schema from a Go struct like InferSchema, and returns it as an AvroSchema.
+func InferSchemaValue(fallbackTag string, v interface{}, opts ...Option) (AvroSchema, error) {
+	if v == nil {
+		return AvroSchema{}, errNilValue
+	}
+
+	schema, err := InferSchemaWithOptions(v, append([]Option{WithFallbackTag(fallbackTag)}, opts...)...)
+	if err != nil {
+		return AvroSchema{}, err
+	}
+
+	return NewAvroSchema(schema)
+}
+
+// String returns the JSON form of the schema, as it was given or inferred.
+func (s AvroSchema) String() string {
+	return s.json
+}
+
+// Tree returns the schema as a TypedSchema tree, as returned by ParseSchema.
+func (s AvroSchema) Tree() TypedSchema {
+	return s.tree
+}
+
+// Canonical returns the Parsing Canonical Form of the schema.
+func (s AvroSchema) Canonical() (string, error) {
+	return CanonicalForm(s.json)
+}
+
+// Fingerprint returns the 64-bit Rabin fingerprint of the canonical form of the schema.
+func (s AvroSchema) Fingerprint() (uint64, error) {
+	return Fingerprint(s.json)
+}
+
+// Compatible checks the compatibility of s, as the reader schema, with the writer schema other,
+// as CheckCompatibility does.
+func (s AvroSchema) Compatible(other AvroSchema, mode CompatMode) error {
+	return CheckCompatibility(s.json, other.json, mode)
+}
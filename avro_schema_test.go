@@ -0,0 +1,46 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvroSchema(t *testing.T) {
+	s, err := InferSchemaValue("avro", Person{})
+	assert.NoError(t, err)
+
+	want, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, s.String())
+	assert.Equal(t, "record", s.Tree().Type)
+
+	canonical, err := s.Canonical()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Person","type":"record","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`, canonical)
+
+	fingerprint, err := s.Fingerprint()
+	assert.NoError(t, err)
+
+	same, err := NewAvroSchema(canonical)
+	assert.NoError(t, err)
+
+	sameFingerprint, err := same.Fingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, fingerprint, sameFingerprint)
+
+	assert.NoError(t, s.Compatible(same, CompatFull))
+
+	newer, err := NewAvroSchema(`{"type":"record","name":"Person","fields":[{"name":"name","type":"string"},{"name":"age","type":"long"}]}`)
+	assert.NoError(t, err)
+	assert.NoError(t, newer.Compatible(s, CompatBackward))
+	assert.Error(t, s.Compatible(newer, CompatBackward))
+}
+
+func TestNewAvroSchema_invalid(t *testing.T) {
+	_, err := NewAvroSchema(`{"type":"record"}`)
+	assert.Error(t, err)
+
+	_, err = InferSchemaValue("avro", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// CanonicalForm returns the Parsing Canonical Form of the given avro schema, as defined by the avro specification:
+// the primitive types are reduced to their simple form, the names are fully qualified, the attributes
+// that are irrelevant to parsing (doc, aliases, defaults, logical types...) are stripped and the remaining ones
+// are ordered, without any whitespace.
+//
+// Two schemas with the same canonical form read and write the same binary data.
+func CanonicalForm(schema string) (string, error) {
+	if _, err := goavro.NewCodec(schema); err != nil {
+		return "", fmt.Errorf("parse schema: %w", err)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(schema))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("decode schema: %w", err)
+	}
+
+	var b strings.Builder
+	if err := canonicalize(&b, v, ""); err != nil {
+		return "", fmt.Errorf("canonicalize schema: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// canonicalize writes the canonical form of the decoded schema v, within the enclosing namespace.
+func canonicalize(b *strings.Builder, v interface{}, namespace string) error {
+	switch v := v.(type) {
+	case string:
+		if isPrimitive(v) {
+			writeString(b, v)
+		} else {
+			writeString(b, fullName(v, "", namespace))
+		}
+
+		return nil
+
+	case []interface{}:
+		b.WriteByte('[')
+		for i, branch := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			if err := canonicalize(b, branch, namespace); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+
+		return nil
+
+	case map[string]interface{}:
+		return canonicalizeObject(b, v, namespace)
+	}
+
+	return fmt.Errorf("unexpected schema %v of type %T", v, v)
+}
+
+// canonicalizeObject writes the canonical form of a schema declared as a JSON object.
+func canonicalizeObject(b *strings.Builder, v map[string]interface{}, namespace string) error {
+	typ, ok := v["type"].(string)
+	if !ok {
+		return canonicalize(b, v["type"], namespace)
+	}
+
+	switch typ {
+	case "record", "error":
+		name := namedFullName(v, namespace)
+
+		b.WriteString(`{"name":`)
+		writeString(b, name)
+		b.WriteString(`,"type":`)
+		writeString(b, typ)
+		b.WriteString(`,"fields":[`)
+
+		fields, _ := v["fields"].([]interface{})
+		for i, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("unexpected field %v of %s", f, name)
+			}
+
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			fieldName, _ := field["name"].(string)
+
+			b.WriteString(`{"name":`)
+			writeString(b, fieldName)
+			b.WriteString(`,"type":`)
+			if err := canonicalize(b, field["type"], namespaceOf(name)); err != nil {
+				return fmt.Errorf("field %s of %s: %w", fieldName, name, err)
+			}
+			b.WriteByte('}')
+		}
+		b.WriteString(`]}`)
+
+	case "enum":
+		b.WriteString(`{"name":`)
+		writeString(b, namedFullName(v, namespace))
+		b.WriteString(`,"type":"enum","symbols":[`)
+
+		symbols, _ := v["symbols"].([]interface{})
+		for i, symbol := range symbols {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			s, _ := symbol.(string)
+			writeString(b, s)
+		}
+		b.WriteString(`]}`)
+
+	case "fixed":
+		size, err := strconv.ParseUint(fmt.Sprint(v["size"]), 10, 0)
+		if err != nil {
+			return fmt.Errorf("fixed size: %w", err)
+		}
+
+		b.WriteString(`{"name":`)
+		writeString(b, namedFullName(v, namespace))
+		b.WriteString(`,"type":"fixed","size":`)
+		b.WriteString(strconv.FormatUint(size, 10))
+		b.WriteByte('}')
+
+	case "array":
+		b.WriteString(`{"type":"array","items":`)
+		if err := canonicalize(b, v["items"], namespace); err != nil {
+			return fmt.Errorf("array: %w", err)
+		}
+		b.WriteByte('}')
+
+	case "map":
+		b.WriteString(`{"type":"map","values":`)
+		if err := canonicalize(b, v["values"], namespace); err != nil {
+			return fmt.Errorf("map: %w", err)
+		}
+		b.WriteByte('}')
+
+	default:
+		// primitives and references, along with their logical types, are reduced to their simple form
+		return canonicalize(b, typ, namespace)
+	}
+
+	return nil
+}
+
+// namedFullName returns the full name of the named type declared by v.
+func namedFullName(v map[string]interface{}, namespace string) string {
+	name, _ := v["name"].(string)
+	ns, _ := v["namespace"].(string)
+
+	return fullName(name, ns, namespace)
+}
+
+// fullName qualifies name with its namespace, or the enclosing one, unless it is already a full name.
+func fullName(name, namespace, enclosing string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	return AddNamespace(namespaceOr(namespace, enclosing), name)
+}
+
+// namespaceOf returns the namespace part of a full name.
+func namespaceOf(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+
+	return ""
+}
+
+// isPrimitive tells if name is one of the avro primitive types.
+func isPrimitive(name string) bool {
+	for _, t := range avroBaseTypes {
+		if t == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeString writes s as a JSON string literal, without escaping characters that don't need to be.
+func writeString(b *strings.Builder, s string) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+
+	b.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
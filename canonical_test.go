@@ -0,0 +1,68 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalForm(t *testing.T) {
+	// examples from https://github.com/apache/avro/blob/master/share/test/data/schema-tests.txt
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`"int"`, `"int"`},
+		{`{"type":"int"}`, `"int"`},
+		{`{"type":"long","logicalType":"timestamp-millis"}`, `"long"`},
+		{`[ "int" , {"type":"boolean"} ]`, `["int","boolean"]`},
+		{`{"fields":[{"name":"dummy","type":"int"}], "type":"record", "name":"foo", "namespace":"x.y"}`,
+			`{"name":"x.y.foo","type":"record","fields":[{"name":"dummy","type":"int"}]}`},
+		{`{"fields":[{"name":"dummy","type":"int"}], "type":"record", "name":"a.b.foo", "namespace":"x.y"}`,
+			`{"name":"a.b.foo","type":"record","fields":[{"name":"dummy","type":"int"}]}`},
+		{`{"fields":[{"name":"dummy","type":"int"}], "type":"record", "name":"foo", "doc":"foo", "aliases":["foo","bar"]}`,
+			`{"name":"foo","type":"record","fields":[{"name":"dummy","type":"int"}]}`},
+		{`{"fields": [
+			{"type": "boolean", "aliases": [], "name": "f1", "default": true},
+			{"order": "descending", "name": "f2", "doc": "Hello", "type": "int"}
+		], "type": "record", "name": "foo"}`,
+			`{"name":"foo","type":"record","fields":[{"name":"f1","type":"boolean"},{"name":"f2","type":"int"}]}`},
+		{`{"namespace":"x.y.z", "type":"enum", "name":"foo", "doc":"foo bar", "symbols":["A1", "A2"]}`,
+			`{"name":"x.y.z.foo","type":"enum","symbols":["A1","A2"]}`},
+		{`{"namespace":"x.y.z", "type":"fixed", "name":"foo", "doc":"foo bar", "size":32}`,
+			`{"name":"x.y.z.foo","type":"fixed","size":32}`},
+		{`{ "items":{"type":"null"}, "type":"array"}`, `{"type":"array","items":"null"}`},
+		{`{ "values":"string", "type":"map"}`, `{"type":"map","values":"string"}`},
+		{`{"name":"PigValue","type":"record","fields":[{"name":"value", "type":["null", "int", "long", "PigValue"]}]}`,
+			`{"name":"PigValue","type":"record","fields":[{"name":"value","type":["null","int","long","PigValue"]}]}`},
+		{`{"type":"enum","symbols":["Go","Avro"],"name":"Foo"}`,
+			`{"name":"Foo","type":"enum","symbols":["Go","Avro"]}`},
+		{`{"type":"record","name":"foo","namespace":"bar","fields":[` +
+			`{"name":"baz","type":{"type":"record","name":"baz","fields":[{"name":"hi","type":"int"}]}},` +
+			`{"name":"bye","type":["null","baz"]}]}`,
+			`{"name":"bar.foo","type":"record","fields":[` +
+				`{"name":"baz","type":{"name":"bar.baz","type":"record","fields":[{"name":"hi","type":"int"}]}},` +
+				`{"name":"bye","type":["null","bar.baz"]}]}`},
+	}
+
+	for _, tt := range tests {
+		got, err := CanonicalForm(tt.schema)
+		if assert.NoError(t, err, tt.schema) {
+			assert.Equal(t, tt.want, got, tt.schema)
+		}
+	}
+}
+
+func TestCanonicalForm_inferred(t *testing.T) {
+	schema, err := InferSchemaWithOptions(Documented{}, WithFallbackTag("avro"), WithNamespace("com.acme"), WithDoc("A documented record"))
+	assert.NoError(t, err)
+
+	got, err := CanonicalForm(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"com.acme.Documented","type":"record","fields":[{"name":"id","type":"string"},{"name":"notes","type":"string"}]}`, got)
+}
+
+func TestCanonicalForm_invalid(t *testing.T) {
+	_, err := CanonicalForm(`{"type":"record"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+// Command avrocado prints the avro schema inferred from a Go type.
+//
+// Usage:
+//
+//	avrocado [-fallback-tag json] [-namespace com.acme] [-indent "  "] github.com/acme/model.User
+//
+// It must be run within a module depending on both the package of the type and avrocado:
+// it generates a program inferring the schema of the type in a temporary directory of the current directory,
+// then builds and runs it.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// config holds the type to infer the schema of and the inference options.
+type config struct {
+	Package     string
+	Type        string
+	FallbackTag string
+	Namespace   string
+	Indent      string
+}
+
+// errFailed is returned when the generated program fails, after it printed its error.
+var errFailed = errors.New("infer schema failed")
+
+var programTemplate = template.Must(template.New("program").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+
+	avro "github.com/leboncoin/avrocado"
+
+	pkg {{printf "%q" .Package}}
+)
+
+func main() {
+	opts := []avro.Option{
+		avro.WithNamespace({{printf "%q" .Namespace}}),
+		avro.WithIndent({{printf "%q" .Indent}}),
+	}
+
+	schema, err := avro.InferSchemaFor[pkg.{{.Type}}]({{printf "%q" .FallbackTag}}, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(schema)
+}
+`))
+
+func main() {
+	var c config
+
+	flag.StringVar(&c.FallbackTag, "fallback-tag", "", "name of the struct tag to use if the avro tag is not present")
+	flag.StringVar(&c.Namespace, "namespace", "", "namespace of the top-level record")
+	flag.StringVar(&c.Indent, "indent", "", "string to indent the schema with, minified if empty")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <package path>.<type name>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	if c.Package, c.Type, err = splitType(flag.Arg(0)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if err := run(c); err != nil {
+		if err != errFailed {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+// splitType splits a type qualified by its package path, such as github.com/acme/model.User.
+func splitType(qualified string) (string, string, error) {
+	i := strings.LastIndex(qualified, ".")
+	if i <= 0 || i < strings.LastIndex(qualified, "/") || i == len(qualified)-1 {
+		return "", "", fmt.Errorf("invalid type %q, expected <package path>.<type name>", qualified)
+	}
+
+	if !token.IsIdentifier(qualified[i+1:]) {
+		return "", "", fmt.Errorf("invalid type name %q", qualified[i+1:])
+	}
+
+	return qualified[:i], qualified[i+1:], nil
+}
+
+// program returns the source of the program printing the schema inferred from the configured type.
+func program(c config) ([]byte, error) {
+	var b bytes.Buffer
+	if err := programTemplate.Execute(&b, c); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// run generates and runs the program printing the schema inferred from the configured type.
+func run(c config) error {
+	src, err := program(c)
+	if err != nil {
+		return fmt.Errorf("generate program: %w", err)
+	}
+
+	// the program is generated in the current directory, to be built within its module
+	dir, err := os.MkdirTemp(".", "avrocado_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o600); err != nil {
+		return err
+	}
+
+	bin := filepath.Join(dir, "avrocado")
+
+	build := exec.Command("go", "build", "-o", bin, "./"+filepath.ToSlash(dir))
+	build.Stderr = os.Stderr
+
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("build program: %w", err)
+	}
+
+	// the program prints its own errors
+	cmd := exec.Command(bin)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return errFailed
+	}
+
+	return nil
+}
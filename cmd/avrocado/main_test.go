@@ -0,0 +1,33 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitType(t *testing.T) {
+	pkg, typ, err := splitType("github.com/acme/model.User")
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/acme/model", pkg)
+	assert.Equal(t, "User", typ)
+
+	for _, invalid := range []string{"User", "github.com/acme.io/model", "github.com/acme/model.", ".User"} {
+		_, _, err := splitType(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	src, err := program(config{Package: "github.com/acme/model", Type: "User", FallbackTag: "json", Namespace: "com.acme", Indent: "\t"})
+	assert.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), `pkg "github.com/acme/model"`)
+	assert.Contains(t, string(src), `avro.InferSchemaFor[pkg.User]("json", opts...)`)
+	assert.Contains(t, string(src), `avro.WithNamespace("com.acme")`)
+	assert.Contains(t, string(src), `avro.WithIndent("\t")`)
+}
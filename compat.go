@@ -0,0 +1,245 @@
+package avro
+
+import "fmt"
+
+// CompatMode is the direction in which CheckCompatibility checks two schemas.
+type CompatMode int
+
+const (
+	// CompatBackward checks that the data written with the writer schema can be read with the reader schema.
+	CompatBackward CompatMode = iota
+	// CompatForward checks that the data written with the reader schema can be read with the writer schema.
+	CompatForward
+	// CompatFull checks both the backward and forward compatibility.
+	CompatFull
+)
+
+// CheckCompatibility checks that the schemas are compatible in the given mode, following the avro schema resolution
+// rules: the reader fields missing from the writer need a default, the writer fields missing from the reader
+// are ignored, the numbers can be promoted from int to long, float and double, and the strings to bytes and back.
+//
+// It returns the first incompatibility along with the path of the field, e.g. address.zip: int can't be read as string.
+func CheckCompatibility(reader, writer string, mode CompatMode) error {
+	r, rp, err := parseSchema(reader)
+	if err != nil {
+		return fmt.Errorf("parse reader schema: %w", err)
+	}
+
+	w, wp, err := parseSchema(writer)
+	if err != nil {
+		return fmt.Errorf("parse writer schema: %w", err)
+	}
+
+	backward := compatChecker{reader: rp.named, writer: wp.named, visited: make(map[[2]string]bool)}
+	forward := compatChecker{reader: wp.named, writer: rp.named, visited: make(map[[2]string]bool)}
+
+	switch mode {
+	case CompatBackward:
+		return backward.check(r.schema(), w.schema(), "")
+	case CompatForward:
+		return forward.check(w.schema(), r.schema(), "")
+	case CompatFull:
+		if err := backward.check(r.schema(), w.schema(), ""); err != nil {
+			return fmt.Errorf("backward: %w", err)
+		}
+
+		if err := forward.check(w.schema(), r.schema(), ""); err != nil {
+			return fmt.Errorf("forward: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown compatibility mode %d", mode)
+}
+
+// compatChecker checks that a parsed reader schema can read the data of a parsed writer schema.
+type compatChecker struct {
+	reader map[string]TypedSchema
+	writer map[string]TypedSchema
+	// visited holds the pairs of reader and writer records already checked, or being checked
+	visited map[[2]string]bool
+}
+
+// promotions holds the writer primitive types each reader primitive type can read, besides itself.
+var promotions = map[string][]string{
+	"long":   {"int"},
+	"float":  {"int", "long"},
+	"double": {"int", "long", "float"},
+	"string": {"bytes"},
+	"bytes":  {"string"},
+}
+
+// check checks that the reader type r can read the data of the writer type w.
+func (c *compatChecker) check(r, w interface{}, path string) error {
+	r, w = unionNode(r), unionNode(w)
+
+	if branches, ok := w.([]interface{}); ok {
+		// any branch may have been written
+		for _, branch := range branches {
+			if err := c.check(r, branch, path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if branches, ok := r.([]interface{}); ok {
+		for _, branch := range branches {
+			if c.check(branch, w, path) == nil {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%s: %s can't be read as any branch of the union", pathOrRoot(path), typeName(resolveNode(w, c.writer)))
+	}
+
+	rs, ws := resolveNode(r, c.reader), resolveNode(w, c.writer)
+
+	if rs.Type != ws.Type {
+		for _, promoted := range promotions[fmt.Sprint(rs.Type)] {
+			if ws.Type == promoted {
+				return nil
+			}
+		}
+
+		return c.incompatible(rs, ws, path)
+	}
+
+	switch rs.Type {
+	case "record", "error":
+		if !sameName(rs, ws) {
+			return c.incompatible(rs, ws, path)
+		}
+
+		return c.checkRecord(rs, ws, path)
+
+	case "enum":
+		if !sameName(rs, ws) {
+			return c.incompatible(rs, ws, path)
+		}
+
+		if rs.Default != nil {
+			return nil
+		}
+
+		for _, symbol := range ws.Symbols {
+			if !isSymbol(rs.Symbols, symbol) {
+				return fmt.Errorf("%s: symbol %s of %s is missing from the reader, which has no default", pathOrRoot(path), symbol, typeName(ws))
+			}
+		}
+
+	case "fixed":
+		if !sameName(rs, ws) {
+			return c.incompatible(rs, ws, path)
+		}
+
+		if rs.Size != ws.Size {
+			return fmt.Errorf("%s: %s of %d bytes can't be read as %d bytes", pathOrRoot(path), typeName(ws), ws.Size, rs.Size)
+		}
+
+	case "array":
+		return c.check(rs.Items, ws.Items, path+"[]")
+
+	case "map":
+		return c.check(rs.Values, ws.Values, path+"[]")
+	}
+
+	return nil
+}
+
+// checkRecord checks that the reader record r can read the data of the writer record w.
+func (c *compatChecker) checkRecord(r, w TypedSchema, path string) error {
+	key := [2]string{typeName(r), typeName(w)}
+	if c.visited[key] {
+		return nil
+	}
+
+	c.visited[key] = true
+
+	for _, rf := range r.Fields {
+		wf, ok := writerField(rf, w)
+		if !ok {
+			if rf.Default == nil {
+				return fmt.Errorf("%s: field missing from the writer has no default", joinPath(path, rf.Name))
+			}
+
+			continue
+		}
+
+		if err := c.check(rf.Type, wf.Type, joinPath(path, rf.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// incompatible returns the error of the writer type w not being readable as the reader type r.
+func (c *compatChecker) incompatible(r, w TypedSchema, path string) error {
+	return fmt.Errorf("%s: %s can't be read as %s", pathOrRoot(path), typeName(w), typeName(r))
+}
+
+// writerField returns the field of the writer record w matching the reader field rf, by name or alias.
+func writerField(rf TypedSchema, w TypedSchema) (TypedSchema, bool) {
+	for _, name := range append([]string{rf.Name}, rf.Aliases...) {
+		for _, wf := range w.Fields {
+			if wf.Name == name {
+				return wf, true
+			}
+		}
+	}
+
+	return TypedSchema{}, false
+}
+
+// sameName tells if the reader named type r matches the writer named type w, by unqualified name or alias.
+func sameName(r, w TypedSchema) bool {
+	if r.Name == w.Name {
+		return true
+	}
+
+	full := AddNamespace(w.Namespace, w.Name)
+	for _, alias := range r.Aliases {
+		if alias == w.Name || fullName(alias, "", r.Namespace) == full {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveNode returns the TypedSchema of a parsed type which isn't a union: the definition of the named types,
+// and the underlying type of the logical ones.
+func resolveNode(typ interface{}, named map[string]TypedSchema) TypedSchema {
+	switch typ := typ.(type) {
+	case string:
+		if s, ok := named[typ]; ok {
+			return s
+		}
+
+		return TypedSchema{Type: typ}
+
+	case TypedSchema:
+		switch typ.Type {
+		case "record", "error", "enum", "fixed", "array", "map":
+			return typ
+		}
+
+		return resolveNode(typ.Type, named)
+	}
+
+	return TypedSchema{Type: typ}
+}
+
+// unionNode returns the union declared by a TypedSchema, or typ itself.
+func unionNode(typ interface{}) interface{} {
+	if s, ok := typ.(TypedSchema); ok {
+		if branches, ok := s.Type.([]interface{}); ok {
+			return branches
+		}
+	}
+
+	return typ
+}
@@ -0,0 +1,125 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const compatV1 = `{"type":"record","name":"User","namespace":"com.example","fields":[
+	{"name":"id","type":"int"},
+	{"name":"name","type":"string"},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}
+]}`
+
+func TestCheckCompatibility(t *testing.T) {
+	// id promoted to long, email added with a default
+	v2 := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"long"},
+		{"name":"name","type":"string"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE","BANNED"]}},
+		{"name":"email","type":["null","string"],"default":null}
+	]}`
+
+	assert.NoError(t, CheckCompatibility(v2, compatV1, CompatBackward))
+
+	err := CheckCompatibility(v2, compatV1, CompatForward)
+	if assert.Error(t, err) {
+		assert.Equal(t, "id: long can't be read as int", err.Error())
+	}
+
+	err = CheckCompatibility(v2, compatV1, CompatFull)
+	if assert.Error(t, err) {
+		assert.Equal(t, "forward: id: long can't be read as int", err.Error())
+	}
+}
+
+func TestCheckCompatibility_full(t *testing.T) {
+	v2 := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":"int"},
+		{"name":"name","type":"string"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}},
+		{"name":"tags","type":{"type":"array","items":"string"},"default":[]}
+	]}`
+
+	assert.NoError(t, CheckCompatibility(v2, compatV1, CompatFull))
+}
+
+func TestCheckCompatibility_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader string
+		err    string
+	}{
+		{
+			name: "added field without default",
+			reader: `{"type":"record","name":"User","namespace":"com.example","fields":[
+				{"name":"id","type":"int"},{"name":"name","type":"string"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}},
+				{"name":"email","type":"string"}
+			]}`,
+			err: "email: field missing from the writer has no default",
+		},
+		{
+			name: "removed enum symbol",
+			reader: `{"type":"record","name":"User","namespace":"com.example","fields":[
+				{"name":"id","type":"int"},{"name":"name","type":"string"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE"]}}
+			]}`,
+			err: "status: symbol INACTIVE of com.example.Status is missing from the reader, which has no default",
+		},
+		{
+			name: "changed type",
+			reader: `{"type":"record","name":"User","namespace":"com.example","fields":[
+				{"name":"id","type":"string"},{"name":"name","type":"string"},
+				{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}
+			]}`,
+			err: "id: int can't be read as string",
+		},
+		{
+			name: "renamed record",
+			reader: `{"type":"record","name":"Account","namespace":"com.example","fields":[
+				{"name":"id","type":"int"}
+			]}`,
+			err: "value: com.example.User can't be read as com.example.Account",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCompatibility(tt.reader, compatV1, CompatBackward)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestCheckCompatibility_aliases_and_unions(t *testing.T) {
+	reader := `{"type":"record","name":"Account","namespace":"com.example","aliases":["User"],"fields":[
+		{"name":"user_id","aliases":["id"],"type":["null","long"]},
+		{"name":"name","type":["null","string"]},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}
+	]}`
+
+	assert.NoError(t, CheckCompatibility(reader, compatV1, CompatBackward))
+
+	writer := `{"type":"record","name":"User","namespace":"com.example","fields":[
+		{"name":"id","type":["int","string"]},{"name":"name","type":"string"},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","INACTIVE"]}}
+	]}`
+
+	err := CheckCompatibility(compatV1, writer, CompatBackward)
+	if assert.Error(t, err) {
+		assert.Equal(t, "id: string can't be read as int", err.Error())
+	}
+}
+
+func TestCheckCompatibility_recursive(t *testing.T) {
+	schema := `{"type":"record","name":"Node","fields":[
+		{"name":"value","type":"int"},
+		{"name":"next","type":["null","Node"]}
+	]}`
+
+	assert.NoError(t, CheckCompatibility(schema, schema, CompatFull))
+}
@@ -0,0 +1,30 @@
+package avro
+
+import "fmt"
+
+// confluentHeaderLength is the length of the magic byte and schema ID prefixing a Confluent payload.
+const confluentHeaderLength = 5
+
+// ConfluentEncode frames the avro binary data in the Confluent wire format: the magic byte,
+// followed by the schema ID on 4 bytes in DefaultEndianness and the data.
+func ConfluentEncode(schemaID int, data []byte) []byte {
+	payload := make([]byte, confluentHeaderLength, confluentHeaderLength+len(data))
+	payload[0] = MagicByte
+	DefaultEndianness.PutUint32(payload[1:], uint32(schemaID))
+
+	return append(payload, data...)
+}
+
+// ConfluentDecode splits a payload in the Confluent wire format into its schema ID and avro binary data.
+// The data shares the memory of the payload.
+func ConfluentDecode(payload []byte) (schemaID int, data []byte, err error) {
+	if len(payload) < confluentHeaderLength {
+		return 0, nil, fmt.Errorf("payload of %d bytes is too short for the %d bytes header", len(payload), confluentHeaderLength)
+	}
+
+	if payload[0] != MagicByte {
+		return 0, nil, fmt.Errorf("the parsed magic byte %q is not correct (expected %q)", payload[0], MagicByte)
+	}
+
+	return int(int32(DefaultEndianness.Uint32(payload[1:]))), payload[confluentHeaderLength:], nil
+}
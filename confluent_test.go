@@ -0,0 +1,36 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfluentEncode(t *testing.T) {
+	data, err := Marshal(`"string"`, "avrocado")
+	assert.NoError(t, err)
+
+	payload := ConfluentEncode(258, data)
+	assert.Equal(t, append([]byte{0x0, 0x0, 0x0, 0x1, 0x2}, data...), payload)
+
+	id, decoded, err := ConfluentDecode(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, 258, id)
+	assert.Equal(t, data, decoded)
+
+	var s string
+	assert.NoError(t, Unmarshal(`"string"`, decoded, &s))
+	assert.Equal(t, "avrocado", s)
+}
+
+func TestConfluentDecode_errors(t *testing.T) {
+	_, _, err := ConfluentDecode([]byte{0x0, 0x0, 0x1})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "too short")
+	}
+
+	_, _, err = ConfluentDecode([]byte{0x1, 0x0, 0x0, 0x0, 0x1, 0x2})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "magic byte")
+	}
+}
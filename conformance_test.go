@@ -0,0 +1,111 @@
+//go:build conformance
+
+package avro
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// The conformance tests use goavro as an oracle of the avro specification: the inferred schemas must be parsed
+// by goavro, and the data encoded by Marshal must be decoded by goavro and encoded back to the same bytes.
+// Run them with go test -tags conformance.
+
+func TestConformance(t *testing.T) {
+	street := "Rue de Rivoli"
+	color := Color("BLUE")
+
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"primitives", Location{Street: street}},
+		{"named types", Customer{
+			Home:    Location{Street: street},
+			Billing: &Location{Street: "Main Street"},
+			Tier:    "GOLD",
+			Key:     [8]byte{1, 2, 3, 4, 5, 6, 7, 8},
+			History: []Location{{Street: street}},
+		}},
+		{"references", Shipment{
+			From:  Location{Street: street},
+			Stops: map[string]Location{"lunch": {Street: "Main Street"}},
+		}},
+		{"recursive types", Node{Value: 1, Next: &Node{Value: 2}, Children: []Node{{Value: 3}}}},
+		{"embedded structs", Document{Audit: Audit{CreatedBy: "jane", Version: 3}, revision: &revision{Revision: 7}, Title: "Notes"}},
+		{"enums", Palette{Color: "RED", Shade: "LIGHT", Accent: &color}},
+		{"defaults", Defaults{Count: 1, Ratio: 0.25, Label: "some", Tags: []string{"a"}, Level: "HIGH"}},
+		{"nested collections", Nested{
+			Tags:     map[string][]int32{"a": {1, 2}},
+			Rows:     []map[string]string{{"k": "v"}},
+			Override: map[string]interface{}{"k": []interface{}{"v", nil}},
+			Matrix:   []interface{}{map[string][]int64{"k": {1}}, nil},
+			Either:   []string{"a"},
+			Deep:     map[string][]map[string]int32{"a": {{"b": 1}}},
+		}},
+		{"unions", Envelope{Payload: int32(42)}},
+		{"stable output", Catalog{
+			Document: Document{revision: &revision{}},
+			Shipment: Shipment{From: Location{Street: street}},
+			Defaults: Defaults{Level: "LOW"},
+			Index:    map[string][]*Location{"a": {nil, {Street: street}}},
+		}},
+		{"dates and times of day", Opening{
+			Date:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			Opens:   time.Date(0, 1, 1, 9, 30, 0, 0, time.UTC),
+			Closes:  18 * time.Hour,
+			Holiday: Day{Year: 2024, Month: time.December, Day: 25},
+		}},
+		{"big numbers", Ledger{Count: *big.NewInt(12), Exact: *big.NewInt(-7), Ratio: *big.NewFloat(0.5)}},
+		{"custom attributes", Charge{Amount: 1200, Card: "4111"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := InferSchema("avro", test.v)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			codec, err := goavro.NewCodec(schema)
+			if !assert.NoError(t, err, "goavro rejects the inferred schema %s", schema) {
+				return
+			}
+
+			b, err := Marshal(schema, test.v)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			native, rest, err := codec.NativeFromBinary(b)
+			if !assert.NoError(t, err, "goavro can't decode the encoded data") {
+				return
+			}
+			assert.Empty(t, rest)
+
+			encoded, err := codec.BinaryFromNative(nil, native)
+			assert.NoError(t, err)
+			assert.Equal(t, b, encoded)
+		})
+	}
+}
+
+func TestConformance_parsed_schemas(t *testing.T) {
+	for _, v := range []interface{}{Customer{}, Node{}, Catalog{}, Opening{}, Charge{}} {
+		schema, err := InferSchema("avro", v)
+		assert.NoError(t, err)
+
+		tree, err := ParseSchema(schema)
+		assert.NoError(t, err)
+
+		reparsed, err := marshalSchema(tree, "")
+		assert.NoError(t, err)
+
+		_, err = goavro.NewCodec(reparsed)
+		assert.NoError(t, err, "goavro rejects the parsed schema %s", reparsed)
+	}
+}
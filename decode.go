@@ -0,0 +1,499 @@
+package avro
+
+import (
+	"database/sql"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	bytesType   = reflect.TypeOf([]byte(nil))
+	byteType    = bytesType.Elem()
+)
+
+// Unmarshal decodes the avro binary data written with the schema into the Go value pointed to by v,
+// following the same conventions as Marshal: the record fields are mapped to the struct fields by their tag,
+// the null branch of a union is decoded as a nil pointer, the timestamps as time.Time and the decimals as big.Rat.
+// A long without logical type is decoded into a time.Time as milliseconds since the epoch, as Marshal writes it.
+// The types implementing AvroUnmarshaler decode their own data.
+func Unmarshal(schema string, data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal into non-pointer or nil %T", v)
+	}
+
+	c, err := compileSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := c.codec.NativeFromBinary(data)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	return c.assign(native, rv.Elem())
+}
+
+// assign sets dst to the goavro native value decoded for the schema.
+func (c *compiledSchema) assign(native interface{}, dst reflect.Value) error {
+	dec := decoder{c.validator()}
+
+	return dec.assign(c.root, native, dst, "")
+}
+
+// decoder assigns the native form of goavro to Go values, along a parsed schema.
+type decoder struct {
+	validator
+}
+
+// assign sets dst to the native value decoded for the parsed schema typ.
+func (dec *decoder) assign(typ interface{}, native interface{}, dst reflect.Value, path string) error {
+	// the value of a union is decoded for the branch it was written with
+	if branches, ok := typ.([]interface{}); ok && native != nil {
+		branch, value, err := unionValue(branches, native)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+
+		typ, native = branch, value
+	}
+
+	if dst.CanAddr() && dst.Addr().Type().Implements(scannerType) && !dec.isComposite(typ) {
+		if err := dst.Addr().Interface().(sql.Scanner).Scan(native); err != nil {
+			return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+
+		return nil
+	}
+
+	if fields, ok := registeredOptional(dst.Type()); ok && fields.check(dst.Type()) == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		if native == nil {
+			return nil
+		}
+
+		dst.FieldByName(fields.present).SetBool(true)
+
+		return dec.assign(typ, native, dst.FieldByName(fields.value), path)
+	}
+
+	if dst.Kind() != reflect.Ptr && dst.CanAddr() && dst.Addr().Type().Implements(avroUnmarshalerType) {
+		return dec.unmarshalAvro(dst.Addr().Interface().(AvroUnmarshaler), typ, native, path)
+	}
+
+	if native == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+
+		return fmt.Errorf("%s: cannot assign null to %s", pathOrRoot(path), dst.Type())
+	}
+
+	if s, ok := native.(string); ok && dst.Type() == errorType {
+		dst.Set(reflect.ValueOf(errors.New(s)))
+		return nil
+	}
+
+	if impls, ok := registeredUnion(dst.Type()); ok {
+		return dec.assignImplementation(impls, typ, native, dst, path)
+	}
+
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(native))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+
+		return dec.assign(typ, native, dst.Elem(), path)
+	}
+
+	switch typ := typ.(type) {
+	case string:
+		if named, ok := dec.named[typ]; ok {
+			return dec.assignSchema(named, native, dst, path)
+		}
+
+		return assignPrimitive(native, dst, path)
+
+	case TypedSchema:
+		return dec.assignSchema(typ, native, dst, path)
+	}
+
+	return fmt.Errorf("%s: unexpected schema %v", pathOrRoot(path), typ)
+}
+
+// assignImplementation sets the interface dst of a registered union to a new value of its implementation named
+// after the record typ the native value was written as.
+func (dec *decoder) assignImplementation(impls []reflect.Type, typ interface{}, native interface{}, dst reflect.Value, path string) error {
+	s := resolveNode(typ, dec.named)
+
+	for _, impl := range impls {
+		t := impl
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Name() != s.Name {
+			continue
+		}
+
+		v := reflect.New(t)
+		if err := dec.assign(typ, native, v.Elem(), path); err != nil {
+			return err
+		}
+
+		if impl.Kind() != reflect.Ptr {
+			v = v.Elem()
+		}
+
+		dst.Set(v)
+
+		return nil
+	}
+
+	return fmt.Errorf("%s: no implementation of %s is named %s", pathOrRoot(path), dst.Type(), typeName(s))
+}
+
+// unionValue returns the branch of the union a goavro union value was written with, along with its value.
+func unionValue(branches []interface{}, native interface{}) (interface{}, interface{}, error) {
+	union, ok := native.(map[string]interface{})
+	if !ok || len(union) != 1 {
+		return nil, nil, fmt.Errorf("unexpected union value %v", native)
+	}
+
+	for name, value := range union {
+		for _, branch := range branches {
+			if goavroName(branch) == name {
+				return branch, value, nil
+			}
+		}
+
+		return nil, nil, fmt.Errorf("union has no %s branch", name)
+	}
+
+	return nil, nil, errors.New("empty union value")
+}
+
+// assignSchema sets dst to the native value decoded for a complex type.
+func (dec *decoder) assignSchema(s TypedSchema, native interface{}, dst reflect.Value, path string) error {
+	if r, ok := native.(*big.Rat); ok {
+		return assignDecimal(r, dst, path)
+	}
+
+	if t, ok := native.(time.Time); ok && setMessageTime(dst, t) {
+		return nil
+	}
+
+	if isCalendarType(s.LogicalType) && dst.Type() != durationType {
+		return assignCalendar(s.LogicalType, native, dst, path)
+	}
+
+	switch s.Type {
+	case "record", "error":
+		record, ok := native.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unexpected record value %v", pathOrRoot(path), native)
+		}
+
+		return dec.assignRecord(s, record, dst, path)
+
+	case "fixed":
+		b, ok := native.([]byte)
+		if !ok {
+			return fmt.Errorf("%s: unexpected fixed value %v", pathOrRoot(path), native)
+		}
+
+		if dst.Kind() == reflect.Array && dst.Type().Elem().Kind() == reflect.Uint8 {
+			if dst.Len() != len(b) {
+				return fmt.Errorf("%s: cannot assign %d bytes to %s", pathOrRoot(path), len(b), dst.Type())
+			}
+
+			setBytes(dst, b)
+
+			return nil
+		}
+
+		return assignPrimitive(b, dst, path)
+
+	case "array":
+		items, ok := native.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unexpected array value %v", pathOrRoot(path), native)
+		}
+
+		switch dst.Kind() {
+		case reflect.Slice:
+			dst.Set(reflect.MakeSlice(dst.Type(), len(items), len(items)))
+		case reflect.Array:
+			if dst.Len() < len(items) {
+				return fmt.Errorf("%s: cannot assign %d items to %s", pathOrRoot(path), len(items), dst.Type())
+			}
+		default:
+			return fmt.Errorf("%s: cannot assign array to %s", pathOrRoot(path), dst.Type())
+		}
+
+		for i, item := range items {
+			if err := dec.assign(s.Items, item, dst.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case "map":
+		values, ok := native.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unexpected map value %v", pathOrRoot(path), native)
+		}
+
+		if dst.Kind() != reflect.Map || !isMapKey(dst.Type().Key()) {
+			return fmt.Errorf("%s: cannot assign map to %s", pathOrRoot(path), dst.Type())
+		}
+
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), len(values)))
+
+		for key, value := range values {
+			k, err := parseMapKey(key, dst.Type().Key())
+			if err != nil {
+				return fmt.Errorf("%s[%q]: %w", pathOrRoot(path), key, err)
+			}
+
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := dec.assign(s.Values, value, elem, fmt.Sprintf("%s[%q]", path, key)); err != nil {
+				return err
+			}
+
+			dst.SetMapIndex(k, elem)
+		}
+
+		return nil
+
+	case "enum":
+		return assignPrimitive(native, dst, path)
+	}
+
+	return dec.assign(s.Type, native, dst, path)
+}
+
+// assignRecord sets the struct, or map, dst to the native record decoded for the record s.
+func (dec *decoder) assignRecord(s TypedSchema, record map[string]interface{}, dst reflect.Value, path string) error {
+	switch {
+	case dst.Kind() == reflect.Struct:
+		indexes, err := dec.inferrer.fieldIndexes(dst.Type())
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+
+		for _, f := range s.Fields {
+			index, ok := indexes[f.Name]
+			if !ok {
+				continue
+			}
+
+			if err := dec.assign(f.Type, record[f.Name], fieldByIndex(dst, index), joinPath(path, f.Name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case dst.Kind() == reflect.Map && dst.Type().Key().Kind() == reflect.String:
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), len(s.Fields)))
+
+		for _, f := range s.Fields {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := dec.assign(f.Type, record[f.Name], elem, joinPath(path, f.Name)); err != nil {
+				return err
+			}
+
+			dst.SetMapIndex(reflect.ValueOf(f.Name).Convert(dst.Type().Key()), elem)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%s: cannot assign record %s to %s", pathOrRoot(path), typeName(s), dst.Type())
+}
+
+// assignPrimitive sets dst to the native value of a primitive type, converting it to the type of dst if needed.
+func assignPrimitive(native interface{}, dst reflect.Value, path string) error {
+	v := reflect.ValueOf(native)
+
+	switch {
+	case v.Type().AssignableTo(dst.Type()):
+		dst.Set(v)
+
+	case isInteger(v) && isInteger(dst):
+		i := integer(v)
+		if dst.Kind() >= reflect.Uint && dst.Kind() <= reflect.Uint64 {
+			if i < 0 || dst.OverflowUint(uint64(i)) {
+				return fmt.Errorf("%s: %d overflows %s", pathOrRoot(path), i, dst.Type())
+			}
+
+			dst.SetUint(uint64(i))
+		} else {
+			if dst.OverflowInt(i) {
+				return fmt.Errorf("%s: %d overflows %s", pathOrRoot(path), i, dst.Type())
+			}
+
+			dst.SetInt(i)
+		}
+
+	case (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64) && (dst.Kind() == reflect.Float32 || dst.Kind() == reflect.Float64):
+		dst.SetFloat(v.Float())
+
+	// a long without logical type holds the milliseconds since the epoch that Marshal writes for a time.Time
+	case v.Kind() == reflect.Int64 && dst.Type() == timeType:
+		dst.Set(reflect.ValueOf(time.UnixMilli(v.Int()).UTC()))
+
+	case isInteger(v) && dst.Type() == bigIntType:
+		dst.Set(reflect.ValueOf(*big.NewInt(integer(v))))
+
+	case (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64) && dst.Type() == bigFloatType:
+		if math.IsNaN(v.Float()) {
+			return fmt.Errorf("%s: cannot assign NaN to big.Float", pathOrRoot(path))
+		}
+
+		dst.Set(reflect.ValueOf(*big.NewFloat(v.Float())))
+
+	case v.Type() == bytesType && dst.Kind() == reflect.Slice && isBytes(dst):
+		dst.Set(reflect.MakeSlice(dst.Type(), v.Len(), v.Len()))
+		setBytes(dst, native.([]byte))
+
+	case v.Kind() == dst.Kind() && v.Type().ConvertibleTo(dst.Type()):
+		dst.Set(v.Convert(dst.Type()))
+
+	default:
+		return fmt.Errorf("%s: cannot assign %s to %s", pathOrRoot(path), v.Type(), dst.Type())
+	}
+
+	return nil
+}
+
+// assignDecimal sets the big.Rat, or big.Int, dst to the decoded decimal r.
+func assignDecimal(r *big.Rat, dst reflect.Value, path string) error {
+	switch dst.Type() {
+	case bigRatType:
+		dst.Set(reflect.ValueOf(*new(big.Rat).Set(r)))
+	case bigIntType:
+		if !r.IsInt() {
+			return fmt.Errorf("%s: cannot assign %s to big.Int", pathOrRoot(path), r.RatString())
+		}
+
+		dst.Set(reflect.ValueOf(*new(big.Int).Set(r.Num())))
+	default:
+		return fmt.Errorf("%s: cannot assign decimal to %s", pathOrRoot(path), dst.Type())
+	}
+
+	return nil
+}
+
+// parseMapKey returns the Go map key of type t of the avro map key, the reverse of mapKey.
+func parseMapKey(key string, t reflect.Type) (reflect.Value, error) {
+	k := reflect.New(t)
+
+	switch {
+	case t.Kind() == reflect.String:
+		return reflect.ValueOf(key).Convert(t), nil
+
+	case k.Type().Implements(textUnmarshalerType):
+		if err := k.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return k, err
+		}
+
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		u, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return k, fmt.Errorf("cannot assign key to %s: %w", t, err)
+		}
+
+		k.Elem().SetUint(u)
+
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return k, fmt.Errorf("cannot assign key to %s: %w", t, err)
+		}
+
+		k.Elem().SetInt(i)
+
+	default:
+		return k, fmt.Errorf("cannot assign key to %s, which doesn't implement encoding.TextUnmarshaler", t)
+	}
+
+	return k.Elem(), nil
+}
+
+// assignCalendar sets the time.Time, or the type whose text form follows the layout of the logical type, dst
+// to the native date or time of day. The times of day are set on the zero date.
+func assignCalendar(logicalType string, native interface{}, dst reflect.Value, path string) error {
+	var t time.Time
+
+	switch native := native.(type) {
+	case time.Time:
+		t = native
+	case time.Duration:
+		t = time.Time{}.Add(native)
+	default:
+		return fmt.Errorf("%s: unexpected %s value %v", pathOrRoot(path), logicalType, native)
+	}
+
+	switch {
+	case dst.Type() == timeType:
+		dst.Set(reflect.ValueOf(t))
+
+	case dst.CanAddr() && dst.Addr().Type().Implements(textUnmarshalerType):
+		err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(t.Format(calendarLayouts[logicalType])))
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+
+	default:
+		return fmt.Errorf("%s: cannot assign %s to %s", pathOrRoot(path), logicalType, dst.Type())
+	}
+
+	return nil
+}
+
+// setBytes copies b to the byte slice or array dst of the same length, whose elements may be of a named byte type.
+func setBytes(dst reflect.Value, b []byte) {
+	if dst.Type().Elem() == byteType {
+		reflect.Copy(dst, reflect.ValueOf(b))
+		return
+	}
+
+	for i, c := range b {
+		dst.Index(i).SetUint(uint64(c))
+	}
+}
+
+// fieldByIndex returns the nested field of the struct v by index, allocating the nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
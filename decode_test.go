@@ -0,0 +1,287 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var got Person
+	err := Unmarshal(`{"type":"record","name":"Person","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`,
+		[]byte{0x08, 'J', 'a', 'n', 'e', 0x54}, &got)
+	assert.NoError(t, err)
+	assert.Equal(t, Person{Name: "Jane", Age: 42}, got)
+}
+
+func TestUnmarshal_round_trip(t *testing.T) {
+	schema, err := InferSchema("avro", Order{})
+	assert.NoError(t, err)
+
+	coupon := "WELCOME"
+	want := Order{
+		ID:       1,
+		Customer: "jane",
+		Coupon:   &coupon,
+		Items:    []OrderItem{{Sku: "A-1", Quantity: 2, Price: 9.99}},
+		Labels:   map[string]string{"channel": "web"},
+		Status:   "GREEN",
+		Checksum: [4]byte{1, 2, 3, 4},
+		Paid:     true,
+		Created:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Total:    *big.NewRat(1998, 100),
+		Weight:   1.5,
+	}
+
+	b, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Order
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Equal(t, want, got)
+
+	want.Coupon = nil
+	b, err = Marshal(schema, want)
+	assert.NoError(t, err)
+
+	got = Order{Coupon: &coupon}
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_sql_null_types(t *testing.T) {
+	schema, err := InferSchema("avro", Row{})
+	assert.NoError(t, err)
+
+	want := Row{}
+	want.Name.String, want.Name.Valid = "jane", true
+	want.Updated.Time, want.Updated.Valid = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), true
+
+	b, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Row
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Equal(t, want, got)
+}
+
+func (m *Meta) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("bad src %T", src)
+	}
+
+	return json.Unmarshal(b, m)
+}
+
+func TestUnmarshal_record_scanner(t *testing.T) {
+	schema, err := InferSchema("avro", Listing{})
+	assert.NoError(t, err)
+
+	// a sql.Scanner is decoded as its record rather than scanning the decoded value
+	for _, want := range []Listing{{M: Meta{A: 1, B: "x"}, Prev: &Meta{A: 2}}, {}} {
+		b, err := Marshal(schema, want)
+		assert.NoError(t, err)
+
+		var got Listing
+		assert.NoError(t, Unmarshal(schema, b, &got))
+		assert.Equal(t, want, got)
+	}
+
+	// it still scans the primitive types
+	b, err := Marshal(`"bytes"`, []byte(`{"a":3}`))
+	assert.NoError(t, err)
+
+	var m Meta
+	assert.NoError(t, Unmarshal(`"bytes"`, b, &m))
+	assert.Equal(t, Meta{A: 3}, m)
+}
+
+func TestUnmarshal_interface(t *testing.T) {
+	schema := `{"type":"record","name":"R","fields":[{"name":"id","type":["null","long","string"]}]}`
+
+	b, err := Marshal(schema, map[string]interface{}{"id": "abc"})
+	assert.NoError(t, err)
+
+	var got map[string]interface{}
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Equal(t, map[string]interface{}{"id": "abc"}, got)
+}
+
+func TestUnmarshal_errors(t *testing.T) {
+	schema := `{"type":"record","name":"R","fields":[{"name":"id","type":["null","string","long"]}]}`
+
+	b, err := Marshal(schema, map[string]interface{}{"id": int64(42)})
+	assert.NoError(t, err)
+
+	var got struct {
+		ID *string `avro:"id"`
+	}
+
+	err = Unmarshal(schema, b, &got)
+	if assert.Error(t, err) {
+		assert.Equal(t, "id: cannot assign int64 to string", err.Error())
+	}
+
+	assert.Error(t, Unmarshal(schema, b, got))
+	assert.Error(t, Unmarshal(schema, []byte{0x02}, &got))
+}
+
+func TestUnmarshal_interface_union(t *testing.T) {
+	schema, err := InferSchema("avro", Envelope{})
+	assert.NoError(t, err)
+
+	for _, payload := range []interface{}{"text", int32(42), nil} {
+		data, err := Marshal(schema, Envelope{Payload: payload})
+		assert.NoError(t, err)
+
+		var got Envelope
+		assert.NoError(t, Unmarshal(schema, data, &got))
+		assert.Equal(t, Envelope{Payload: payload}, got)
+	}
+}
+
+func TestUnmarshal_named_bytes(t *testing.T) {
+	schema, err := InferSchema("avro", Payloads{})
+	assert.NoError(t, err)
+
+	want := Payloads{
+		Raw:    json.RawMessage(`{"a":1}`),
+		Blob:   Blob("blob"),
+		Octets: []Octet{1, 2, 3},
+		IDs:    IDs{"a", "b"},
+	}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Payloads
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, want, got)
+}
+
+func TestUnmarshal_stringified_map_keys(t *testing.T) {
+	schema, err := InferSchemaWithOptions(Grid{}, WithStringifyMapKeys())
+	assert.NoError(t, err)
+
+	want := Grid{
+		Rows:  map[int]string{-1: "header", 2: "body"},
+		Ports: map[uint16]bool{443: true},
+		Cells: map[Coord]float64{{X: 1, Y: 2}: 0.5},
+	}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var native map[string]map[string]interface{}
+	assert.NoError(t, Unmarshal(schema, data, &native))
+	assert.Equal(t, map[string]interface{}{"-1": "header", "2": "body"}, native["rows"])
+	assert.Equal(t, map[string]interface{}{"1,2": 0.5}, native["cells"])
+
+	var got Grid
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, want, got)
+
+	var overflow struct {
+		Ports map[uint8]bool `avro:"ports"`
+	}
+
+	err = Unmarshal(schema, data, &overflow)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `ports["443"]: cannot assign key to uint8`)
+	}
+}
+
+func TestUnmarshal_dates_and_times_of_day(t *testing.T) {
+	schema, err := InferSchema("avro", Opening{})
+	assert.NoError(t, err)
+
+	micros := time.Time{}.Add(13*time.Hour + 250*time.Microsecond)
+	want := Opening{
+		Date:    time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		Opens:   time.Time{}.Add(9*time.Hour + 30*time.Minute),
+		Closes:  18 * time.Hour,
+		Holiday: Day{Year: 2024, Month: time.December, Day: 25},
+		Micros:  &micros,
+	}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Opening
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, want, got)
+
+	// the time of day of a time.Time is kept, its date dropped
+	want.Opens = time.Date(2024, time.March, 1, 9, 30, 0, 0, time.UTC)
+
+	data, err = Marshal(schema, want)
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, time.Time{}.Add(9*time.Hour+30*time.Minute), got.Opens)
+}
+
+func TestUnmarshal_big_numbers(t *testing.T) {
+	schema, err := InferSchema("avro", Ledger{})
+	assert.NoError(t, err)
+
+	want := Ledger{Count: *big.NewInt(-42), Balance: big.NewInt(1 << 40), Exact: *big.NewInt(7), Ratio: *big.NewFloat(0.25)}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Ledger
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, 0, want.Count.Cmp(&got.Count))
+	assert.Equal(t, 0, want.Balance.Cmp(got.Balance))
+	assert.Equal(t, 0, want.Exact.Cmp(&got.Exact))
+	assert.Equal(t, 0, want.Ratio.Cmp(&got.Ratio))
+	assert.Nil(t, got.Rate)
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	_, err = Marshal(schema, Ledger{Count: *huge})
+	assert.EqualError(t, err, "count: 123456789012345678901234567890 is out of the long range")
+}
+
+func TestUnmarshal_error_fields(t *testing.T) {
+	schema, err := InferSchema("avro", Job{})
+	assert.NoError(t, err)
+
+	b, err := Marshal(schema, Job{ID: "1", Failure: errors.New("timeout"), Cause: fmt.Errorf("dial: %w", io.EOF)})
+	assert.NoError(t, err)
+
+	var job Job
+	assert.NoError(t, Unmarshal(schema, b, &job))
+	assert.Equal(t, "1", job.ID)
+	assert.EqualError(t, job.Failure, "timeout")
+	assert.EqualError(t, job.Cause, "dial: EOF")
+
+	b, err = Marshal(schema, Job{ID: "2", Cause: io.EOF})
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(schema, b, &job))
+	assert.NoError(t, job.Failure)
+}
+
+func TestUnmarshal_optionals(t *testing.T) {
+	RegisterOptional("github.com/leboncoin/avrocado.Maybe", "Value", "Set")
+	defer RegisterOptional("github.com/leboncoin/avrocado.Maybe", "", "")
+
+	schema, err := InferSchema("avro", Member{})
+	assert.NoError(t, err)
+
+	want := Member{Age: Maybe[int]{Value: 42, Set: true}, Home: Maybe[Location]{Value: Location{Street: "Main Street"}, Set: true}}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	got := Member{Nickname: Maybe[string]{Value: "stale", Set: true}}
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, want, got)
+}
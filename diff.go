@@ -0,0 +1,196 @@
+package avro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaDiff is the difference between an old and a new schema, field by field. The fields are identified by their
+// path within the new schema, or within the old one for the removed fields, e.g. address.zip.
+type SchemaDiff struct {
+	// Added holds the fields of the new schema missing from the old one.
+	Added []FieldDiff
+	// Removed holds the fields of the old schema missing from the new one.
+	Removed []FieldDiff
+	// Renamed holds the fields of the new schema matching a field of the old one by alias.
+	Renamed []FieldDiff
+	// Changed holds the fields whose type changed.
+	Changed []FieldDiff
+}
+
+// FieldDiff is a field of a SchemaDiff, along with its old and new types written as type expressions
+// such as null|string or array<long>. The old type is empty for an added field, and the new one for a removed field.
+type FieldDiff struct {
+	Path string
+	// OldPath is the path of the field within the old schema, which differs from Path for a renamed field.
+	// It is empty for the added and removed fields.
+	OldPath string
+	OldType string
+	NewType string
+}
+
+// DiffSchemas returns the difference between the old schema a and the new schema b. The fields of the nested
+// records, which may be nullable, are compared as well, and the fields of b are matched to those of a by name
+// or alias: a field renamed without an alias is removed and added.
+func DiffSchemas(a, b string) (SchemaDiff, error) {
+	old, op, err := parseSchema(a)
+	if err != nil {
+		return SchemaDiff{}, fmt.Errorf("parse old schema: %w", err)
+	}
+
+	updated, up, err := parseSchema(b)
+	if err != nil {
+		return SchemaDiff{}, fmt.Errorf("parse new schema: %w", err)
+	}
+
+	d := differ{oldNamed: op.named, newNamed: up.named, visited: make(map[[2]string]bool)}
+	d.compare(old.schema(), updated.schema(), "", "")
+
+	return d.diff, nil
+}
+
+// Empty tells if the schemas have the same fields, of the same types.
+func (d SchemaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.Changed) == 0
+}
+
+// String renders the difference one field per line, e.g. changed age: int to long.
+func (d SchemaDiff) String() string {
+	var b strings.Builder
+
+	for _, f := range d.Added {
+		fmt.Fprintf(&b, "added %s: %s\n", f.Path, f.NewType)
+	}
+
+	for _, f := range d.Removed {
+		fmt.Fprintf(&b, "removed %s: %s\n", f.Path, f.OldType)
+	}
+
+	for _, f := range d.Renamed {
+		fmt.Fprintf(&b, "renamed %s to %s\n", f.OldPath, f.Path)
+	}
+
+	for _, f := range d.Changed {
+		fmt.Fprintf(&b, "changed %s: %s to %s\n", pathOrRoot(f.Path), f.OldType, f.NewType)
+	}
+
+	return b.String()
+}
+
+// differ compares two parsed schemas.
+type differ struct {
+	oldNamed, newNamed map[string]TypedSchema
+	// visited holds the pairs of old and new records already compared, or being compared
+	visited map[[2]string]bool
+	diff    SchemaDiff
+}
+
+// compare compares the old type a at oldPath with the new type b at path.
+func (d *differ) compare(a, b interface{}, oldPath, path string) {
+	if oldType, newType := typeExpression(a), typeExpression(b); oldType != newType {
+		d.diff.Changed = append(d.diff.Changed, FieldDiff{Path: path, OldPath: oldPath, OldType: oldType, NewType: newType})
+	}
+
+	ra, aok := nestedRecord(a, d.oldNamed)
+	rb, bok := nestedRecord(b, d.newNamed)
+	if !aok || !bok {
+		return
+	}
+
+	key := [2]string{typeName(ra), typeName(rb)}
+	if d.visited[key] {
+		return
+	}
+
+	d.visited[key] = true
+
+	matched := make(map[string]bool, len(ra.Fields))
+
+	for _, bf := range rb.Fields {
+		af, ok := writerField(bf, ra)
+		if !ok {
+			d.diff.Added = append(d.diff.Added, FieldDiff{Path: joinPath(path, bf.Name), NewType: typeExpression(bf.Type)})
+			continue
+		}
+
+		matched[af.Name] = true
+
+		if af.Name != bf.Name {
+			d.diff.Renamed = append(d.diff.Renamed, FieldDiff{
+				Path:    joinPath(path, bf.Name),
+				OldPath: joinPath(oldPath, af.Name),
+				OldType: typeExpression(af.Type),
+				NewType: typeExpression(bf.Type),
+			})
+		}
+
+		d.compare(af.Type, bf.Type, joinPath(oldPath, af.Name), joinPath(path, bf.Name))
+	}
+
+	for _, af := range ra.Fields {
+		if !matched[af.Name] {
+			d.diff.Removed = append(d.diff.Removed, FieldDiff{Path: joinPath(oldPath, af.Name), OldType: typeExpression(af.Type)})
+		}
+	}
+}
+
+// nestedRecord returns the record declared, or referenced, by the parsed type typ or by its only branch besides null.
+func nestedRecord(typ interface{}, named map[string]TypedSchema) (TypedSchema, bool) {
+	if branches, ok := unionNode(typ).([]interface{}); ok {
+		if len(branches) != 2 || (branches[0] != "null" && branches[1] != "null") {
+			return TypedSchema{}, false
+		}
+
+		typ = branches[0]
+		if typ == "null" {
+			typ = branches[1]
+		}
+	}
+
+	s := resolveNode(typ, named)
+
+	return s, s.Type == "record" || s.Type == "error"
+}
+
+// typeExpression writes the parsed type typ as a type expression of the type= tag option, the named types being
+// written by full name and the logical types after their underlying type, e.g. long (timestamp-millis).
+func typeExpression(typ interface{}) string {
+	switch typ := typ.(type) {
+	case string:
+		return typ
+
+	case []interface{}:
+		branches := make([]string, len(typ))
+		for i, branch := range typ {
+			branches[i] = typeExpression(branch)
+		}
+
+		return strings.Join(branches, "|")
+
+	case TypedSchema:
+		if isNamed(typ) {
+			return AddNamespace(typ.Namespace, typ.Name)
+		}
+
+		var expr string
+		switch typ.Type {
+		case "array":
+			expr = "array<" + typeExpression(typ.Items) + ">"
+		case "map":
+			expr = "map<" + typeExpression(typ.Values) + ">"
+		default:
+			expr = typeExpression(typ.Type)
+		}
+
+		switch {
+		case typ.LogicalType == "decimal":
+			return fmt.Sprintf("%s (decimal(%d,%d))", expr, typ.Precision, typ.Scale)
+		case typ.LogicalType != "":
+			return fmt.Sprintf("%s (%s)", expr, typ.LogicalType)
+		}
+
+		return expr
+	}
+
+	return fmt.Sprint(typ)
+}
@@ -0,0 +1,66 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	old := `{"type":"record","name":"User","fields":[
+		{"name":"fullname","type":"string"},
+		{"name":"age","type":"int"},
+		{"name":"phone","type":"string"},
+		{"name":"home","type":["null",{"type":"record","name":"Address","fields":[{"name":"zip","type":"int"}]}]},
+		{"name":"joined","type":{"type":"long","logicalType":"timestamp-millis"}}
+	]}`
+	updated := `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string","aliases":["fullname"]},
+		{"name":"age","type":"long"},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"home","type":["null",{"type":"record","name":"Address","fields":[{"name":"zip","type":"string"},{"name":"tags","type":{"type":"array","items":"string"}}]}]},
+		{"name":"joined","type":{"type":"long","logicalType":"timestamp-micros"}}
+	]}`
+
+	got, err := DiffSchemas(old, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaDiff{
+		Added: []FieldDiff{
+			{Path: "email", NewType: "null|string"},
+			{Path: "home.tags", NewType: "array<string>"},
+		},
+		Removed: []FieldDiff{{Path: "phone", OldType: "string"}},
+		Renamed: []FieldDiff{{Path: "name", OldPath: "fullname", OldType: "string", NewType: "string"}},
+		Changed: []FieldDiff{
+			{Path: "age", OldPath: "age", OldType: "int", NewType: "long"},
+			{Path: "home.zip", OldPath: "home.zip", OldType: "int", NewType: "string"},
+			{Path: "joined", OldPath: "joined", OldType: "long (timestamp-millis)", NewType: "long (timestamp-micros)"},
+		},
+	}, got)
+	assert.False(t, got.Empty())
+
+	assert.Equal(t, "added email: null|string\n"+
+		"added home.tags: array<string>\n"+
+		"removed phone: string\n"+
+		"renamed fullname to name\n"+
+		"changed age: int to long\n"+
+		"changed home.zip: int to string\n"+
+		"changed joined: long (timestamp-millis) to long (timestamp-micros)\n", got.String())
+}
+
+func TestDiffSchemas_same(t *testing.T) {
+	schema, err := InferSchema("avro", Node{})
+	assert.NoError(t, err)
+
+	got, err := DiffSchemas(schema, schema)
+	assert.NoError(t, err)
+	assert.True(t, got.Empty())
+	assert.Equal(t, "", got.String())
+
+	got, err = DiffSchemas(`"int"`, `["null","int"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed value: int to null|int\n", got.String())
+
+	_, err = DiffSchemas(`{"type":"record"}`, schema)
+	assert.EqualError(t, err, "parse old schema: record without a name")
+}
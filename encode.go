@@ -0,0 +1,373 @@
+package avro
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// calendarLayouts are the layouts of the text form of the dates and times of day, as written by civil.Date and civil.Time.
+var calendarLayouts = map[string]string{
+	"date":        "2006-01-02",
+	"time-millis": "15:04:05.999999999",
+	"time-micros": "15:04:05.999999999",
+}
+
+// Marshal encodes the Go value v to avro binary according to the schema, following the same conventions
+// as InferSchema: the struct fields are mapped to the record fields by their tag, the nil pointers are encoded
+// as the null branch of their union, the time.Time values as timestamps and the big.Rat values as decimals.
+// The types implementing AvroMarshaler encode their own data.
+//
+// The nil slices and maps are encoded as the null branch of a union having one, so that they are decoded back
+// as nil while the empty ones are decoded as empty, and as empty arrays, maps or bytes otherwise.
+func Marshal(schema string, v interface{}) ([]byte, error) {
+	c, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	native, err := c.native(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	return b, nil
+}
+
+// compiledSchema is a schema parsed by both goavro and the package, to encode and decode Go values.
+type compiledSchema struct {
+	codec *goavro.Codec
+	root  interface{}
+	named map[string]TypedSchema
+}
+
+// compileSchema parses the JSON avro schema.
+func compileSchema(schema string) (*compiledSchema, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+
+	root, p, err := parseSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+
+	return &compiledSchema{codec: codec, root: root.schema(), named: p.named}, nil
+}
+
+// validator returns a new validator of the values of the schema.
+func (c *compiledSchema) validator() validator {
+	return validator{
+		named:    c.named,
+		inferrer: &inferrer{named: make(map[string]definition), visiting: make(map[reflect.Type]bool)},
+	}
+}
+
+// native validates the Go value v and returns its goavro native form.
+func (c *compiledSchema) native(v interface{}) (interface{}, error) {
+	enc := encoder{c.validator()}
+
+	if err := enc.validate(c.root, reflect.ValueOf(v), ""); err != nil {
+		return nil, err
+	}
+
+	return enc.native(c.root, reflect.ValueOf(v), "")
+}
+
+// encoder turns a Go value into the native form of goavro, along a parsed schema.
+type encoder struct {
+	validator
+}
+
+// native returns the goavro native form of the value v for the parsed schema typ.
+// The value must have been validated against typ beforehand.
+func (enc *encoder) native(typ interface{}, v reflect.Value, path string) (interface{}, error) {
+	if _, ok := typ.([]interface{}); !ok {
+		if m, ok := avroMarshaler(v); ok {
+			return enc.marshalAvro(m, typ, path)
+		}
+	}
+
+	v = indirect(v, !enc.isComposite(typ))
+
+	switch typ := typ.(type) {
+	case string:
+		return enc.nativeName(typ, v, path)
+
+	case []interface{}:
+		if !v.IsValid() || isNilCollection(v) && hasNull(typ) {
+			return nil, nil
+		}
+
+		for _, branch := range enc.unionBranches(typ, v) {
+			if enc.validate(branch, v, path) != nil {
+				continue
+			}
+
+			native, err := enc.native(branch, v, path)
+			if err != nil {
+				return nil, err
+			}
+
+			return goavro.Union(goavroName(branch), native), nil
+		}
+
+	case TypedSchema:
+		return enc.nativeSchema(typ, v, path)
+	}
+
+	return nil, fmt.Errorf("%s: unexpected schema %v", pathOrRoot(path), typ)
+}
+
+// nativeName returns the native form of the value v for a primitive or named type.
+func (enc *encoder) nativeName(name string, v reflect.Value, path string) (interface{}, error) {
+	switch name {
+	case "null":
+		return nil, nil
+	case "boolean":
+		return v.Bool(), nil
+	case "int":
+		return int32(integer(v)), nil
+	case "long":
+		switch v.Type() {
+		case timeType:
+			return v.Interface().(time.Time).UnixNano() / int64(time.Millisecond), nil
+		case bigIntType:
+			i := v.Interface().(big.Int)
+			return i.Int64(), nil
+		}
+
+		return integer(v), nil
+	case "float":
+		return float32(float(v)), nil
+	case "double":
+		return float(v), nil
+	case "bytes":
+		return nativeBytes(v), nil
+	case "string":
+		return v.String(), nil
+	}
+
+	return enc.nativeSchema(enc.named[name], v, path)
+}
+
+// nativeSchema returns the native form of the value v for a complex type.
+func (enc *encoder) nativeSchema(s TypedSchema, v reflect.Value, path string) (interface{}, error) {
+	switch s.LogicalType {
+	case "timestamp-millis", "timestamp-micros":
+		if v.Type() == timeType {
+			return v.Interface(), nil
+		}
+
+		if t, ok := messageTime(v); ok {
+			return t, nil
+		}
+
+	case "date":
+		if t, ok, _ := calendarTime(s.LogicalType, v); ok {
+			return t, nil
+		}
+
+	case "time-millis", "time-micros":
+		if v.Type() == durationType {
+			return v.Interface(), nil
+		}
+
+		if t, ok, _ := calendarTime(s.LogicalType, v); ok {
+			return timeOfDay(t), nil
+		}
+
+	case "decimal":
+		switch v.Type() {
+		case bigRatType:
+			r := v.Interface().(big.Rat)
+			return &r, nil
+		case bigIntType:
+			i := v.Interface().(big.Int)
+			return new(big.Rat).SetInt(&i), nil
+		}
+	}
+
+	switch s.Type {
+	case "record", "error":
+		fields, err := enc.fields(s, v, path)
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]interface{}, len(s.Fields))
+		for i, f := range s.Fields {
+			// goavro encodes the default of the missing fields
+			if !fields[i].IsValid() && f.Default != nil {
+				continue
+			}
+
+			native, err := enc.native(f.Type, fields[i], joinPath(path, f.Name))
+			if err != nil {
+				return nil, err
+			}
+
+			record[f.Name] = native
+		}
+
+		return record, nil
+
+	case "enum":
+		return v.String(), nil
+
+	case "fixed":
+		return nativeBytes(v), nil
+
+	case "array":
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			native, err := enc.native(s.Items, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+
+			items[i] = native
+		}
+
+		return items, nil
+
+	case "map":
+		values := make(map[string]interface{}, v.Len())
+
+		iter := v.MapRange()
+		for iter.Next() {
+			key := mapKey(iter.Key())
+
+			native, err := enc.native(s.Values, iter.Value(), fmt.Sprintf("%s[%q]", path, key))
+			if err != nil {
+				return nil, err
+			}
+
+			values[key] = native
+		}
+
+		return values, nil
+	}
+
+	return enc.native(s.Type, v, path)
+}
+
+// goavroName returns the name goavro gives to the parsed type typ within a union.
+func goavroName(typ interface{}) string {
+	s, ok := typ.(TypedSchema)
+	if !ok {
+		return unionBranch(typ)
+	}
+
+	if isNamed(s) {
+		return AddNamespace(s.Namespace, s.Name)
+	}
+
+	switch {
+	case s.Type == "long" && (s.LogicalType == "timestamp-millis" || s.LogicalType == "timestamp-micros" || s.LogicalType == "time-micros"),
+		s.Type == "int" && (s.LogicalType == "time-millis" || s.LogicalType == "date"),
+		s.Type == "bytes" && s.LogicalType == "decimal":
+		return fmt.Sprintf("%s.%s", s.Type, s.LogicalType)
+	}
+
+	return unionBranch(s.Type)
+}
+
+// joinPath returns the path of the field name within path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// isCalendarType tells if the logical type is a date or a time of day.
+func isCalendarType(logicalType string) bool {
+	_, ok := calendarLayouts[logicalType]
+	return ok
+}
+
+// calendarTime returns the time.Time of the value v of a date or time of day, either a time.Time or a type whose
+// text form follows the layout of the logical type. It tells if v is such a value, and returns the error of parsing it.
+func calendarTime(logicalType string, v reflect.Value) (time.Time, bool, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time), true, nil
+	}
+
+	if !v.Type().Implements(textMarshalerType) {
+		return time.Time{}, false, nil
+	}
+
+	text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	t, err := time.Parse(calendarLayouts[logicalType], string(text))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is not a %s: %w", text, logicalType, err)
+	}
+
+	return t, true, nil
+}
+
+// timeOfDay returns the time elapsed since the midnight of t.
+func timeOfDay(t time.Time) time.Duration {
+	hour, min, sec := t.Clock()
+
+	return time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second +
+		time.Duration(t.Nanosecond())
+}
+
+// float returns the float or big.Float v as a float64.
+func float(v reflect.Value) float64 {
+	if v.Type() == bigFloatType {
+		f := v.Interface().(big.Float)
+		x, _ := f.Float64()
+
+		return x
+	}
+
+	return v.Float()
+}
+
+// integer returns the signed or unsigned integer v as an int64.
+func integer(v reflect.Value) int64 {
+	if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64 {
+		return int64(v.Uint())
+	}
+
+	return v.Int()
+}
+
+// nativeBytes returns a copy of the byte slice or array v, whose elements may be of a named byte type.
+func nativeBytes(v reflect.Value) []byte {
+	b := make([]byte, v.Len())
+	if v.Type().Elem() == byteType {
+		reflect.Copy(reflect.ValueOf(b), v)
+		return b
+	}
+
+	for i := range b {
+		b[i] = byte(v.Index(i).Uint())
+	}
+
+	return b
+}
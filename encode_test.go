@@ -0,0 +1,197 @@
+package avro
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type Order struct {
+	ID       int64             `avro:"id"`
+	Customer string            `avro:"customer"`
+	Coupon   *string           `avro:"coupon"`
+	Items    []OrderItem       `avro:"items"`
+	Labels   map[string]string `avro:"labels"`
+	Status   Color             `avro:"status,enum=RED|GREEN|BLUE"`
+	Checksum [4]byte           `avro:"checksum"`
+	Paid     bool              `avro:"paid"`
+	Created  time.Time         `avro:"created"`
+	Total    big.Rat           `avro:"total,precision=10,scale=2"`
+	Weight   float32           `avro:"weight"`
+}
+
+type OrderItem struct {
+	Sku      string  `avro:"sku"`
+	Quantity int32   `avro:"quantity"`
+	Price    float64 `avro:"price"`
+}
+
+func TestMarshal(t *testing.T) {
+	got, err := Marshal(`{"type":"record","name":"Person","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`,
+		Person{Name: "Jane", Age: 42})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 'J', 'a', 'n', 'e', 0x54}, got)
+}
+
+func TestMarshal_inferred(t *testing.T) {
+	schema, err := InferSchema("avro", Order{})
+	assert.NoError(t, err)
+
+	coupon := "WELCOME"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	order := Order{
+		ID:       1,
+		Customer: "jane",
+		Coupon:   &coupon,
+		Items:    []OrderItem{{Sku: "A-1", Quantity: 2, Price: 9.99}},
+		Labels:   map[string]string{"channel": "web"},
+		Status:   "GREEN",
+		Checksum: [4]byte{1, 2, 3, 4},
+		Paid:     true,
+		Created:  created,
+		Total:    *big.NewRat(1998, 100),
+		Weight:   1.5,
+	}
+
+	b, err := Marshal(schema, order)
+	assert.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":       int64(1),
+		"customer": "jane",
+		"coupon":   map[string]interface{}{"string": "WELCOME"},
+		"items":    []interface{}{map[string]interface{}{"sku": "A-1", "quantity": int32(2), "price": 9.99}},
+		"labels":   map[string]interface{}{"channel": "web"},
+		"status":   "GREEN",
+		"checksum": []byte{1, 2, 3, 4},
+		"paid":     true,
+		"created":  created,
+		"total":    big.NewRat(1998, 100),
+		"weight":   float32(1.5),
+	}, native)
+
+	order.Coupon = nil
+	b, err = Marshal(schema, &order)
+	assert.NoError(t, err)
+
+	native, _, err = codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Nil(t, native.(map[string]interface{})["coupon"])
+}
+
+func TestMarshal_unions(t *testing.T) {
+	schema := `["null","int","string",{"type":"long","logicalType":"timestamp-millis"},{"type":"array","items":"string"}]`
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{nil, nil},
+		{int32(42), map[string]interface{}{"int": int32(42)}},
+		{"hello", map[string]interface{}{"string": "hello"}},
+		{at, map[string]interface{}{"long.timestamp-millis": at}},
+		{[]string{"a"}, map[string]interface{}{"array": []interface{}{"a"}}},
+	}
+
+	for _, tt := range tests {
+		b, err := Marshal(schema, tt.value)
+		if assert.NoError(t, err) {
+			native, _, err := codec.NativeFromBinary(b)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, native)
+		}
+	}
+}
+
+func TestMarshal_invalid(t *testing.T) {
+	_, err := Marshal(`{"type":"record","name":"Person","fields":[{"name":"name","type":"string"}]}`, map[string]interface{}{"name": 1})
+	if assert.Error(t, err) {
+		assert.Equal(t, "name: expected string, got int", err.Error())
+	}
+
+	_, err = Marshal(`{"type":"record"}`, nil)
+	assert.Error(t, err)
+}
+
+type Basket struct {
+	Tags   []string          `avro:"tags"`
+	Labels map[string]string `avro:"labels"`
+	Items  []string          `avro:"items"`
+	Raw    []byte            `avro:"raw"`
+}
+
+func TestMarshal_nil_and_empty_collections(t *testing.T) {
+	schema := `{"type":"record","name":"Basket","fields":[
+		{"name":"tags","type":["null",{"type":"array","items":"string"}]},
+		{"name":"labels","type":[{"type":"map","values":"string"},"null"]},
+		{"name":"items","type":{"type":"array","items":"string"}},
+		{"name":"raw","type":["null","bytes"]}
+	]}`
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	b, err := Marshal(schema, Basket{})
+	assert.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"tags": nil, "labels": nil, "items": []interface{}{}, "raw": nil}, native)
+
+	var got Basket
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Nil(t, got.Tags)
+	assert.Nil(t, got.Labels)
+	assert.Equal(t, []string{}, got.Items)
+	assert.Nil(t, got.Raw)
+
+	empty := Basket{Tags: []string{}, Labels: map[string]string{}, Items: []string{}, Raw: []byte{}}
+
+	b, err = Marshal(schema, empty)
+	assert.NoError(t, err)
+
+	native, _, err = codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tags":   map[string]interface{}{"array": []interface{}{}},
+		"labels": map[string]interface{}{"map": map[string]interface{}{}},
+		"items":  []interface{}{},
+		"raw":    map[string]interface{}{"bytes": []byte{}},
+	}, native)
+
+	assert.NoError(t, Unmarshal(schema, b, &got))
+	assert.Equal(t, empty, got)
+}
+
+func TestMarshal_logical_types_in_unions(t *testing.T) {
+	schema, err := InferSchema("avro", Schedule{})
+	assert.NoError(t, err)
+
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+	b, err := Marshal(schema, Schedule{Start: &start, Budget: big.NewRat(1999, 100)})
+	assert.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	// goavro names the branches of the logical types after them
+	native, _, err := codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"start":  map[string]interface{}{"long.timestamp-millis": start},
+		"micros": nil,
+		"day":    nil,
+		"budget": map[string]interface{}{"bytes.decimal": big.NewRat(1999, 100)},
+	}, native)
+}
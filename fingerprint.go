@@ -0,0 +1,104 @@
+package avro
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+)
+
+// rabinEmpty is the CRC-64-AVRO fingerprint of the empty input, as defined by the avro specification.
+const rabinEmpty = uint64(0xc15d213aa4d7a795)
+
+// rabinTable is the lookup table of the CRC-64-AVRO algorithm.
+var rabinTable = func() (table [256]uint64) {
+	for i := range table {
+		fp := uint64(i)
+		for j := 0; j < 8; j++ {
+			fp = (fp >> 1) ^ (rabinEmpty & -(fp & 1))
+		}
+
+		table[i] = fp
+	}
+
+	return table
+}()
+
+// rabin computes the CRC-64-AVRO fingerprint of b.
+func rabin(b []byte) uint64 {
+	fp := rabinEmpty
+	for _, c := range b {
+		fp = (fp >> 8) ^ rabinTable[byte(fp)^c]
+	}
+
+	return fp
+}
+
+// Fingerprint returns the 64-bit Rabin fingerprint (CRC-64-AVRO) of the Parsing Canonical Form of schema,
+// as used to identify schemas in the single object encoding.
+func Fingerprint(schema string) (uint64, error) {
+	canonical, err := CanonicalForm(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	return rabin([]byte(canonical)), nil
+}
+
+// InferSchemaWithFingerprint will infer the avro schema from a Go struct like InferSchema, and return it along
+// with its Fingerprint. The schema is compiled with goavro before being fingerprinted, so that the fragments set by
+// the ref= and schema= tag options are validated as well.
+func InferSchemaWithFingerprint(fallbackTag string, v interface{}, opts ...Option) (string, uint64, error) {
+	if v == nil {
+		return "", 0, errNilValue
+	}
+
+	o := newInferOptions(append([]Option{WithFallbackTag(fallbackTag)}, opts...))
+
+	schema, err := inferSchemaFromType(context.Background(), reflect.TypeOf(v), o)
+	if err != nil {
+		return "", 0, err
+	}
+
+	fp, err := Fingerprint(schema)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return schema, fp, nil
+}
+
+// FingerprintBytes returns the Fingerprint of schema in its little-endian 8-byte form,
+// as written in the header of the single object encoding.
+func FingerprintBytes(schema string) ([]byte, error) {
+	fp, err := Fingerprint(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, fp)
+
+	return b, nil
+}
+
+// FingerprintMD5 returns the MD5 fingerprint of the Parsing Canonical Form of schema.
+func FingerprintMD5(schema string) ([16]byte, error) {
+	canonical, err := CanonicalForm(schema)
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	return md5.Sum([]byte(canonical)), nil
+}
+
+// FingerprintSHA256 returns the SHA-256 fingerprint of the Parsing Canonical Form of schema.
+func FingerprintSHA256(schema string) ([32]byte, error) {
+	canonical, err := CanonicalForm(schema)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return sha256.Sum256([]byte(canonical)), nil
+}
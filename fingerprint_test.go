@@ -0,0 +1,137 @@
+package avro
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	// fingerprints from https://github.com/apache/avro/blob/master/share/test/data/schema-tests.txt
+	tests := []struct {
+		schema string
+		want   int64
+	}{
+		{`"null"`, 7195948357588979594},
+		{`"boolean"`, -6970731678124411036},
+		{`"int"`, 8247732601305521295},
+		{`{"type":"int"}`, 8247732601305521295},
+		{`"long"`, -3434872931120570953},
+		{`"float"`, 5583340709985441680},
+		{`"double"`, -8181574048448539266},
+		{`"bytes"`, 5746618253357095269},
+		{`"string"`, -8142146995180207161},
+	}
+
+	for _, tt := range tests {
+		got, err := Fingerprint(tt.schema)
+		if assert.NoError(t, err, tt.schema) {
+			assert.Equal(t, tt.want, int64(got), tt.schema)
+		}
+	}
+}
+
+func TestFingerprint_goavro(t *testing.T) {
+	schema, err := InferSchema("avro", A{})
+	assert.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	got, err := Fingerprint(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, codec.Rabin, got)
+}
+
+func TestInferSchemaWithFingerprint(t *testing.T) {
+	schema, fp, err := InferSchemaWithFingerprint("avro", Customer{})
+	assert.NoError(t, err)
+
+	want, err := InferSchema("avro", Customer{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, schema)
+
+	got, err := Fingerprint(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, got, fp)
+
+	// the namespace is part of the canonical form
+	namespaced, nfp, err := InferSchemaWithFingerprint("avro", Customer{}, WithNamespace("com.acme"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, fp, nfp)
+
+	got, err = Fingerprint(namespaced)
+	assert.NoError(t, err)
+	assert.Equal(t, got, nfp)
+
+	_, _, err = InferSchemaWithFingerprint("avro", nil)
+	assert.Error(t, err)
+
+	// a reference to a type defined by another schema is inferred, but isn't a schema on its own
+	type Referencing struct {
+		Tier string `avro:"tier,ref=com.acme.Tier"`
+	}
+
+	_, err = InferSchema("avro", Referencing{})
+	assert.NoError(t, err)
+
+	_, _, err = InferSchemaWithFingerprint("avro", Referencing{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "parse schema: ")
+	}
+}
+
+func TestFingerprintBytes(t *testing.T) {
+	got, err := FingerprintBytes(`"int"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x8f, 0x5c, 0x39, 0x3f, 0x1a, 0xd5, 0x75, 0x72}, got)
+
+	_, err = FingerprintBytes(`{"type":"record"}`)
+	assert.Error(t, err)
+}
+
+func TestFingerprintMD5(t *testing.T) {
+	got, err := FingerprintMD5(`{"type":"int"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "ef524ea1b91e73173d938ade36c1db32", hex.EncodeToString(got[:]))
+}
+
+func TestFingerprintSHA256(t *testing.T) {
+	got, err := FingerprintSHA256(`{"type":"int"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "3f2b87a9fe7cc9b13835598c3981cd45e3e355309e5090aa0933d7becb6fba45", hex.EncodeToString(got[:]))
+}
+
+func TestFingerprint_equivalent_schemas(t *testing.T) {
+	a := `{"type":"record","name":"User","namespace":"com.acme","fields":[{"name":"name","type":"string"}]}`
+	b := `{
+		"doc": "A user of the platform",
+		"fields": [{"name": "name", "type": "string", "doc": "Display name", "default": ""}],
+		"name": "User",
+		"namespace": "com.acme",
+		"type": "record"
+	}`
+
+	rabinA, err := Fingerprint(a)
+	assert.NoError(t, err)
+	rabinB, err := Fingerprint(b)
+	assert.NoError(t, err)
+	assert.Equal(t, rabinA, rabinB)
+
+	md5A, err := FingerprintMD5(a)
+	assert.NoError(t, err)
+	md5B, err := FingerprintMD5(b)
+	assert.NoError(t, err)
+	assert.Equal(t, md5A, md5B)
+
+	sha256A, err := FingerprintSHA256(a)
+	assert.NoError(t, err)
+	sha256B, err := FingerprintSHA256(b)
+	assert.NoError(t, err)
+	assert.Equal(t, sha256A, sha256B)
+
+	_, err = FingerprintSHA256(`{"type":"record"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,407 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateGo generates the source of a Go package declaring the structs matching the given avro record schema,
+// tagged so that InferSchema infers the schema back: the names, namespaces, defaults and types are kept. The docs
+// are written as comments, the aliases, orders and custom attributes are dropped, the nullable fields get a null
+// default, and the enums within arrays and maps are written as strings.
+//
+// The nested records are declared as separate structs, the enums and named fixed types as named types,
+// the nullable unions are mapped to pointers and the timestamps and decimals to time.Time and big.Rat.
+// The unions with null last stay so through the type= tag option when their other branch is a primitive
+// or named type, and are written with null first otherwise.
+func GenerateGo(schema string, packageName string) (string, error) {
+	root, p, err := parseSchema(schema)
+	if err != nil {
+		return "", fmt.Errorf("parse schema: %w", err)
+	}
+
+	if root.Type != "record" {
+		return "", errors.New("schema must be a record")
+	}
+
+	g := generator{parser: p, imports: make(map[string]bool), root: AddNamespace(root.Namespace, root.Name)}
+
+	for _, name := range p.records {
+		if err := g.record(p.named[name]); err != nil {
+			return "", fmt.Errorf("record %s: %w", name, err)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "package %s\n\n", packageName)
+
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, strconv.Quote(imp))
+		}
+
+		sort.Strings(imports)
+		fmt.Fprintf(&src, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+
+	src.Write(g.named.Bytes())
+	src.Write(g.structs.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("format source: %w", err)
+	}
+
+	return string(formatted), nil
+}
+
+// generator writes the Go declarations of a parsed schema.
+type generator struct {
+	parser  *schemaParser
+	imports map[string]bool
+	// root is the full name of the root record
+	root string
+	// declared holds the enums and fixed types already declared
+	declared map[string]bool
+	// named holds the declarations of the enums and fixed types
+	named bytes.Buffer
+	// structs holds the declarations of the records
+	structs bytes.Buffer
+}
+
+// record writes the struct declaration of the record s. Its blank field sets the name InferSchema wouldn't infer
+// from the name of the struct, and the namespace of the root record.
+func (g *generator) record(s TypedSchema) error {
+	var fields bytes.Buffer
+
+	var opts []string
+	if exportedName(s.Name) != s.Name {
+		opts = append(opts, "name="+s.Name)
+	}
+
+	if AddNamespace(s.Namespace, s.Name) == g.root && s.Namespace != "" {
+		opts = append(opts, "namespace="+s.Namespace)
+	}
+
+	if len(opts) > 0 {
+		fmt.Fprintf(&fields, "_ struct{} `avro:%s`\n", strconv.Quote(","+strings.Join(opts, ",")))
+	}
+
+	for _, f := range s.Fields {
+		typ, tag, err := g.goType(f.Type, false)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		tag = append(tag, g.namedOptions(f.Type, s.Namespace, true)...)
+		if def, ok := defaultOption(f.Default); ok {
+			tag = append(tag, "default="+def)
+		}
+
+		writeComment(&fields, f.Doc)
+		fmt.Fprintf(&fields, "%s %s `avro:%s`\n", exportedName(f.Name), typ, strconv.Quote(strings.Join(append([]string{f.Name}, tag...), ",")))
+	}
+
+	writeComment(&g.structs, s.Doc)
+	fmt.Fprintf(&g.structs, "type %s struct {\n%s}\n\n", exportedName(s.Name), fields.String())
+
+	return nil
+}
+
+// namedOptions returns the tag options setting the namespace of the named type of a field of the parsed type typ,
+// when it differs from the namespace of the enclosing record, and the name of its enum or fixed type when it differs
+// from the name of the Go type. The names aren't passed down to the items and values, only the namespaces are.
+func (g *generator) namedOptions(typ interface{}, namespace string, direct bool) []string {
+	switch typ := typ.(type) {
+	case string:
+		named, ok := g.parser.named[typ]
+		if !ok {
+			return nil
+		}
+
+		var opts []string
+		if named.Namespace != namespace && named.Namespace != "" {
+			opts = append(opts, "namespace="+named.Namespace)
+		}
+
+		if direct && (named.Type == "enum" || named.Type == "fixed") && exportedName(named.Name) != named.Name {
+			opts = append(opts, "name="+named.Name)
+		}
+
+		return opts
+
+	case []interface{}:
+		if len(typ) == 2 && typ[0] == "null" {
+			return g.namedOptions(typ[1], namespace, direct)
+		}
+
+	case TypedSchema:
+		switch typ.Type {
+		case "array":
+			return g.namedOptions(typ.Items, namespace, false)
+		case "map":
+			return g.namedOptions(typ.Values, namespace, false)
+		case "record", "error", "enum", "fixed":
+			return g.namedOptions(AddNamespace(typ.Namespace, typ.Name), namespace, direct)
+		}
+	}
+
+	return nil
+}
+
+// defaultOption returns the default def of a field as the value of the default= tag option, unless it is null,
+// which InferSchema sets itself on the nullable fields, or it can't be written within a tag, such as a string
+// holding a comma.
+func defaultOption(def interface{}) (string, bool) {
+	var raw string
+
+	switch def := def.(type) {
+	case nil, Null:
+		return "", false
+	case bool:
+		raw = strconv.FormatBool(def)
+	case float64:
+		raw = strconv.FormatFloat(def, 'f', -1, 64)
+	case string:
+		raw = def
+	default:
+		b, err := json.Marshal(def)
+		if err != nil {
+			return "", false
+		}
+
+		raw = string(b)
+	}
+
+	// the options are split on the commas and the struct tags are raw strings
+	if strings.ContainsAny(raw, ",`") || strings.Contains(raw, "='") {
+		return "", false
+	}
+
+	return raw, true
+}
+
+// goType returns the Go type matching the parsed schema typ, along with the tag options it needs to be inferred back.
+func (g *generator) goType(typ interface{}, nullable bool) (string, []string, error) {
+	ptr := ""
+	if nullable {
+		ptr = "*"
+	}
+
+	switch typ := typ.(type) {
+	case string:
+		switch typ {
+		case "boolean":
+			return ptr + "bool", nil, nil
+		case "int":
+			return ptr + "int32", nil, nil
+		case "long":
+			return ptr + "int64", nil, nil
+		case "float":
+			return ptr + "float32", nil, nil
+		case "double":
+			return ptr + "float64", nil, nil
+		case "bytes":
+			return ptr + "[]byte", nil, nil
+		case "string":
+			return ptr + "string", nil, nil
+		case "null":
+			return "", nil, errors.New("null is only supported in unions")
+		}
+
+		named, ok := g.parser.named[typ]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown type %q", typ)
+		}
+
+		switch named.Type {
+		case "enum":
+			g.enum(named)
+
+			tag := []string{"enum=" + strings.Join(named.Symbols, "|")}
+			if def, ok := named.Default.(string); ok {
+				tag = append(tag, "enumdefault="+def)
+			}
+
+			return ptr + exportedName(named.Name), tag, nil
+		case "fixed":
+			g.fixed(named)
+		}
+
+		return ptr + exportedName(named.Name), nil, nil
+
+	case []interface{}:
+		if len(typ) == 2 && (typ[0] == "null" || typ[1] == "null") {
+			branch := typ[1]
+			if typ[1] == "null" {
+				branch = typ[0]
+			}
+
+			typeName, tag, err := g.goType(branch, true)
+
+			// the type= option keeps null last, for the branches it can name
+			if name, ok := branch.(string); ok && typ[1] == "null" && err == nil {
+				return typeName, []string{"type=" + name + "|null"}, nil
+			}
+
+			return typeName, tag, err
+		}
+
+		names := make([]string, 0, len(typ))
+		for _, branch := range typ {
+			name, ok := branch.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("union of %v can't be represented by a struct tag", typ)
+			}
+
+			names = append(names, name)
+		}
+
+		return "interface{}", []string{"type=" + strings.Join(names, "|")}, nil
+
+	case TypedSchema:
+		return g.complexType(typ, ptr)
+	}
+
+	return "", nil, fmt.Errorf("unexpected type %v", typ)
+}
+
+// complexType returns the Go type matching the parsed complex schema s.
+func (g *generator) complexType(s TypedSchema, ptr string) (string, []string, error) {
+	switch {
+	case s.LogicalType == "timestamp-millis" && s.Type == "long":
+		g.imports["time"] = true
+		return ptr + "time.Time", nil, nil
+
+	case s.LogicalType == "timestamp-micros" && s.Type == "long":
+		g.imports["time"] = true
+		return ptr + "time.Time", []string{"logicalType=timestamp-micros"}, nil
+
+	case s.LogicalType == "date" && s.Type == "int", s.LogicalType == "time-millis" && s.Type == "int",
+		s.LogicalType == "time-micros" && s.Type == "long":
+		g.imports["time"] = true
+		return ptr + "time.Time", []string{"logicalType=" + s.LogicalType}, nil
+
+	case s.LogicalType == "decimal" && (s.Type == "bytes" || s.Type == "fixed"):
+		g.imports["math/big"] = true
+
+		tag := []string{"precision=" + strconv.Itoa(s.Precision), "scale=" + strconv.Itoa(s.Scale)}
+		if s.Type == "fixed" {
+			tag = append(tag, "backing=fixed", "size="+strconv.Itoa(s.Size))
+		}
+
+		return ptr + "big.Rat", tag, nil
+	}
+
+	switch s.Type {
+	case "array":
+		items, _, err := g.goType(s.Items, false)
+		if err != nil {
+			return "", nil, fmt.Errorf("array: %w", err)
+		}
+
+		return ptr + "[]" + items, nil, nil
+
+	case "map":
+		values, _, err := g.goType(s.Values, false)
+		if err != nil {
+			return "", nil, fmt.Errorf("map: %w", err)
+		}
+
+		return ptr + "map[string]" + values, nil, nil
+
+	case "record", "error", "enum", "fixed":
+		return g.goType(AddNamespace(s.Namespace, s.Name), ptr != "")
+	}
+
+	// other logical types are represented by their underlying type
+	return g.goType(s.Type, ptr != "")
+}
+
+// enum writes the declaration of the enum s and of its symbols, once.
+func (g *generator) enum(s TypedSchema) {
+	name := exportedName(s.Name)
+	if !g.declare(name) {
+		return
+	}
+
+	writeComment(&g.named, s.Doc)
+	fmt.Fprintf(&g.named, "type %s string\n\n", name)
+
+	g.named.WriteString("const (\n")
+	for _, symbol := range s.Symbols {
+		fmt.Fprintf(&g.named, "%s%s %s = %q\n", name, exportedName(symbol), name, symbol)
+	}
+	g.named.WriteString(")\n\n")
+}
+
+// fixed writes the declaration of the fixed type s, once.
+func (g *generator) fixed(s TypedSchema) {
+	name := exportedName(s.Name)
+	if !g.declare(name) {
+		return
+	}
+
+	writeComment(&g.named, s.Doc)
+	fmt.Fprintf(&g.named, "type %s [%d]byte\n\n", name, s.Size)
+}
+
+// declare tells if the named type hasn't been declared yet, and marks it as declared.
+func (g *generator) declare(name string) bool {
+	if g.declared == nil {
+		g.declared = make(map[string]bool)
+	}
+
+	if g.declared[name] {
+		return false
+	}
+
+	g.declared[name] = true
+
+	return true
+}
+
+// writeComment writes doc as a Go comment.
+func writeComment(b *bytes.Buffer, doc string) {
+	if doc == "" {
+		return
+	}
+
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "// %s\n", line)
+	}
+}
+
+// exportedName turns an avro name into an exported Go identifier, e.g. user_id into UserID.
+func exportedName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, part := range parts {
+		if upper := strings.ToUpper(part); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	return b.String()
+}
+
+// commonInitialisms are the initialisms written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IP": true, "JSON": true, "SQL": true, "URI": true, "URL": true, "UUID": true,
+}
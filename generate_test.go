@@ -0,0 +1,220 @@
+package avro
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateGo(t *testing.T) {
+	got, err := GenerateGo(`{"type":"record","name":"user_profile","namespace":"com.acme","doc":"A user","fields":[
+		{"name":"user_id","type":"long","doc":"Unique identifier"},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"created_at","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"balance","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BANNED"]}},
+		{"name":"hash","type":{"type":"fixed","name":"Hash","size":16}},
+		{"name":"address","type":{"type":"record","name":"Address","fields":[{"name":"city","type":"string"}]}},
+		{"name":"previous","type":["null","Address"]},
+		{"name":"tags","type":{"type":"array","items":"string"}},
+		{"name":"scores","type":{"type":"map","values":"double"}},
+		{"name":"id","type":["int","string"]}
+	]}`, "model")
+	assert.NoError(t, err)
+	assert.Equal(t, "package model\n"+`
+import (
+	"math/big"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusACTIVE Status = "ACTIVE"
+	StatusBANNED Status = "BANNED"
+)
+
+type Hash [16]byte
+
+// A user
+type UserProfile struct {
+	_ struct{} `+"`avro:\",name=user_profile,namespace=com.acme\"`"+`
+	// Unique identifier
+	UserID    int64              `+"`avro:\"user_id\"`"+`
+	Email     *string            `+"`avro:\"email\"`"+`
+	CreatedAt time.Time          `+"`avro:\"created_at\"`"+`
+	Balance   big.Rat            `+"`avro:\"balance,precision=10,scale=2\"`"+`
+	Status    Status             `+"`avro:\"status,enum=ACTIVE|BANNED\"`"+`
+	Hash      Hash               `+"`avro:\"hash\"`"+`
+	Address   Address            `+"`avro:\"address\"`"+`
+	Previous  *Address           `+"`avro:\"previous\"`"+`
+	Tags      []string           `+"`avro:\"tags\"`"+`
+	Scores    map[string]float64 `+"`avro:\"scores\"`"+`
+	ID        interface{}        `+"`avro:\"id,type=int|string\"`"+`
+}
+
+type Address struct {
+	City string `+"`avro:\"city\"`"+`
+}
+`, got)
+}
+
+type Generated struct {
+	ID      int64             `avro:"id"`
+	Name    *string           `avro:"name"`
+	Created time.Time         `avro:"created,logicalType=timestamp-micros"`
+	Labels  map[string]string `avro:"labels"`
+	Parent  *GeneratedParent  `avro:"parent"`
+}
+
+type GeneratedParent struct {
+	Tags []string `avro:"tags"`
+}
+
+func TestGenerateGo_round_trip(t *testing.T) {
+	schema, err := InferSchema("avro", Generated{})
+	assert.NoError(t, err)
+
+	got, err := GenerateGo(schema, "avro")
+	assert.NoError(t, err)
+	assert.Equal(t, "package avro\n"+`
+import (
+	"time"
+)
+
+type Generated struct {
+	ID      int64             `+"`avro:\"id\"`"+`
+	Name    *string           `+"`avro:\"name\"`"+`
+	Created time.Time         `+"`avro:\"created,logicalType=timestamp-micros\"`"+`
+	Labels  map[string]string `+"`avro:\"labels\"`"+`
+	Parent  *GeneratedParent  `+"`avro:\"parent\"`"+`
+}
+
+type GeneratedParent struct {
+	Tags []string `+"`avro:\"tags\"`"+`
+}
+`, got)
+}
+
+func TestGenerateGo_not_a_record(t *testing.T) {
+	_, err := GenerateGo(`{"type":"array","items":"string"}`, "model")
+	assert.Error(t, err)
+
+	_, err = GenerateGo(`{"type":"record","name":"R","fields":[{"name":"f","type":"Unknown"}]}`, "model")
+	assert.Error(t, err)
+}
+
+func TestGenerateGo_dates_and_times_of_day(t *testing.T) {
+	schema, err := InferSchema("avro", Opening{})
+	assert.NoError(t, err)
+
+	got, err := GenerateGo(schema, "avro")
+	assert.NoError(t, err)
+	assert.Contains(t, got, "Date    time.Time  `avro:\"date,logicalType=date\"`")
+	assert.Contains(t, got, "Opens   time.Time  `avro:\"opens,logicalType=time-millis\"`")
+	assert.Contains(t, got, "Holiday time.Time  `avro:\"holiday,logicalType=date\"`")
+	assert.Contains(t, got, "Micros  *time.Time `avro:\"micros,logicalType=time-micros\"`")
+}
+
+func TestGenerateGo_enum_default_symbol(t *testing.T) {
+	schema, err := InferSchema("avro", Ticket{})
+	assert.NoError(t, err)
+
+	got, err := GenerateGo(schema, "avro")
+	assert.NoError(t, err)
+	assert.Contains(t, got, "`avro:\"status,enum=OPEN|CLOSED|UNKNOWN,enumdefault=UNKNOWN,default=OPEN\"`")
+}
+
+func TestGenerateGo_inferred_back(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is required to compile the generated code")
+	}
+
+	schema := `{"type":"record","name":"user_profile","namespace":"com.x","fields":[
+		{"name":"a","type":"int","default":3},
+		{"name":"nick","type":"string","default":"anon"},
+		{"name":"score","type":"double","default":1.5},
+		{"name":"active","type":"boolean","default":true},
+		{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BANNED"]},"default":"ACTIVE"},
+		{"name":"home","type":{"type":"record","name":"postal_address","namespace":"com.y","fields":[
+			{"name":"city","type":"string","default":""}
+		]}},
+		{"name":"work","type":["null","com.y.postal_address"],"default":null},
+		{"name":"motto","type":["string","null"],"default":"carpe diem"},
+		{"name":"hash","type":{"type":"fixed","name":"md5","size":16}},
+		{"name":"tags","type":{"type":"array","items":"string"},"default":[]}
+	]}`
+
+	src, err := GenerateGo(schema, "main")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	root, err := os.Getwd()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the generated package is compiled against this tree, inferring the schema back
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module generated\n\ngo 1.12\n\nrequire github.com/leboncoin/avrocado v0.0.0\n\n" +
+			"replace github.com/leboncoin/avrocado => " + root + "\n",
+		"go.sum":       string(sum),
+		"generated.go": src,
+		"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+
+	avro "github.com/leboncoin/avrocado"
+)
+
+func main() {
+	schema, err := avro.InferSchema("avro", UserProfile{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(schema)
+}
+`,
+	}
+
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		t.Fatalf("run the generated code: %s\n%s", err, exitErr.Stderr)
+	}
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	want, _, err := parseSchema(schema)
+	assert.NoError(t, err)
+
+	got, _, err := parseSchema(string(out))
+	if assert.NoError(t, err, string(out)) {
+		assert.Equal(t, want.schema(), got.schema(), string(out))
+	}
+}
@@ -1,14 +1,18 @@
 module github.com/leboncoin/avrocado
 
-go 1.12
+go 1.18
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fatih/camelcase v1.0.0
-	github.com/fatih/structtag v1.2.0 // indirect
+	github.com/fatih/structtag v1.2.0
+	github.com/golang/snappy v0.0.1
 	github.com/leboncoin/structs v0.0.0-20180308133606-9809b6d3fc5a
 	github.com/linkedin/goavro/v2 v2.9.8
 	github.com/mitchellh/mapstructure v1.1.2
-	github.com/pkg/errors v0.8.1 // indirect
 	github.com/stretchr/testify v1.3.0
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
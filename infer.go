@@ -1,197 +1,1851 @@
 package avro
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/structtag"
 )
 
+// TypedSchema is an avro schema, or a field of a record schema.
+//
+// Type holds the type name, a []interface{} for a union or, for a field, the TypedSchema of the field type.
+// Items and Values hold the same for the items of an array and the values of a map.
+// The other attributes are only set for the types and fields which have them.
+// Props holds the custom attributes, such as "x-team", written alongside the standard ones.
 type TypedSchema struct {
-	Name   string      `json:"name"`
-	Type   interface{} `json:"type"`
-	types  []interface{}
-	items  []interface{}
-	Items  interface{} `json:"items,omitempty"`
-	values []interface{}
-	Values interface{}   `json:"values,omitempty"`
-	Fields []TypedSchema `json:"fields,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Namespace   string                 `json:"namespace,omitempty"`
+	Doc         string                 `json:"doc,omitempty"`
+	Aliases     []string               `json:"aliases,omitempty"`
+	Type        interface{}            `json:"type"`
+	Size        int                    `json:"size,omitempty"`
+	LogicalType string                 `json:"logicalType,omitempty"`
+	Precision   int                    `json:"precision,omitempty"`
+	Scale       int                    `json:"scale,omitempty"`
+	Items       interface{}            `json:"items,omitempty"`
+	Values      interface{}            `json:"values,omitempty"`
+	Fields      []TypedSchema          `json:"fields,omitempty"`
+	Symbols     []string               `json:"symbols,omitempty"`
+	Default     interface{}            `json:"default,omitempty"`
+	Order       string                 `json:"order,omitempty"`
+	Props       map[string]interface{} `json:"-"`
 }
 
+// reservedAttributes are the standard attributes of the schemas and fields, which can't be custom ones.
+var reservedAttributes = map[string]bool{
+	"name": true, "namespace": true, "doc": true, "aliases": true, "type": true, "size": true, "logicalType": true,
+	"precision": true, "scale": true, "items": true, "values": true, "fields": true, "symbols": true, "default": true,
+	"order": true,
+}
+
+// MarshalJSON implements json.Marshaler, writing the custom attributes after the standard ones.
+func (s TypedSchema) MarshalJSON() ([]byte, error) {
+	type plain TypedSchema
+
+	b, err := json.Marshal(plain(s))
+	if err != nil || len(s.Props) == 0 {
+		return b, err
+	}
+
+	props, err := json.Marshal(s.Props)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(b[:len(b)-1], ','), props[1:]...), nil
+}
+
+// Null is the Default of a field defaulting to null, which would otherwise be omitted from the schema.
+type Null struct{}
+
+// MarshalJSON implements json.Marshaler.
+func (Null) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// tagOptions holds the schema related options parsed from a struct field tag.
+type tagOptions struct {
+	types       []string
+	items       []string
+	values      []string
+	logicalType string
+	precision   string
+	scale       string
+	size        string
+	// backing is the bytes or fixed type underlying a decimal, set by backing=
+	backing string
+	symbols []string
+	// enumDefault is the symbol the readers use for the unknown symbols, set by enumdefault=
+	enumDefault string
+	defaultVal  *string
+	doc         string
+	aliases     []string
+	order       string
+	// name overrides the name of the record, enum or fixed type of the field, set by name=.
+	// The name of the tag, as in avro:"foo", always names the field itself.
+	name string
+	// props are the custom attributes set by props=
+	props map[string]interface{}
+	// pii and deprecated flag the field as holding personal data or being deprecated, with custom attributes
+	pii, deprecated bool
+	// ref is the full name of the named type of the field set by ref=, defined by another schema
+	ref string
+	// schema is the JSON schema of the field type set by schema=, spliced in verbatim
+	schema json.RawMessage
+	// omitEmpty is set by the omitempty option of a fallback tag, such as json:"x,omitempty"
+	omitEmpty bool
+	// fieldName is the avro name of the field being inferred
+	fieldName string
+	// scope locates the type being inferred within the schema, as in Artifact_salt_items, to name its anonymous fixeds
+	scope string
+	// namespace of the named types, inherited from the enclosing record unless set by the tag
+	namespace string
+}
+
+// avroNameRegexp matches the names allowed by the avro specification.
+var avroNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// timeSchema returns the schema used to represent a time.Time, a timestamp-millis unless another timestamp,
+// a date or a time of day is requested.
+func timeSchema(logicalType string) (TypedSchema, error) {
+	switch logicalType {
+	case "":
+		return TypedSchema{Type: "long", LogicalType: "timestamp-millis"}, nil
+	case "timestamp-millis", "timestamp-micros", "time-micros":
+		return TypedSchema{Type: "long", LogicalType: logicalType}, nil
+	case "date", "time-millis":
+		return TypedSchema{Type: "int", LogicalType: logicalType}, nil
+	}
+
+	return TypedSchema{}, fmt.Errorf("unsupported logical type for time.Time: %s", logicalType)
+}
+
+// inferDecimal fills s with a decimal logical type backed by bytes, or by a
+// fixed when backing=fixed or a size is given.
+func inferDecimal(s *TypedSchema, opts tagOptions) (err error) {
+	if opts.logicalType != "" && opts.logicalType != "decimal" {
+		return fmt.Errorf("unsupported logical type for %s: %s", s.Name, opts.logicalType)
+	}
+
+	if opts.precision == "" {
+		return errors.New("decimal requires a precision")
+	}
+
+	s.LogicalType = "decimal"
+
+	s.Precision, err = strconv.Atoi(opts.precision)
+	if err != nil || s.Precision <= 0 {
+		return fmt.Errorf("invalid decimal precision: %s", opts.precision)
+	}
+
+	if opts.scale != "" {
+		s.Scale, err = strconv.Atoi(opts.scale)
+		if err != nil || s.Scale < 0 || s.Scale > s.Precision {
+			return fmt.Errorf("invalid decimal scale: %s", opts.scale)
+		}
+	}
+
+	switch opts.backing {
+	case "", "fixed":
+	case "bytes":
+		if opts.size != "" {
+			return errors.New("a bytes backed decimal has no size")
+		}
+	default:
+		return fmt.Errorf("invalid decimal backing %q, must be bytes or fixed", opts.backing)
+	}
+
+	if opts.size == "" {
+		if opts.backing == "fixed" {
+			return errors.New("a fixed backed decimal requires a size")
+		}
+
+		s.Type = "bytes"
+		return nil
+	}
+
+	s.Size, err = strconv.Atoi(opts.size)
+	if err != nil || s.Size <= 0 {
+		return fmt.Errorf("invalid fixed size: %s", opts.size)
+	}
+
+	if max := maxDecimalPrecision(s.Size); s.Precision > max {
+		return fmt.Errorf("fixed size %d can't hold a decimal of precision %d, at most %d", s.Size, s.Precision, max)
+	}
+
+	s.Type = "fixed"
+
+	return nil
+}
+
+// maxDecimalPrecision returns the number of digits always held by size bytes of two's complement,
+// i.e. floor(log10(2^(8*size-1) - 1)).
+func maxDecimalPrecision(size int) int {
+	max := new(big.Int).Lsh(big.NewInt(1), uint(8*size-1))
+	max.Sub(max, big.NewInt(1))
+
+	return len(max.String()) - 1
+}
+
+// elementOptions returns the options of the items or values of the array or map of opts, which are given the
+// logical type of their field, as in []time.Time `avro:"at,logicalType=timestamp-micros"`.
+func (opts tagOptions) elementOptions(kind string) tagOptions {
+	return tagOptions{
+		namespace:   opts.namespace,
+		scope:       generatedName(opts, kind),
+		logicalType: opts.logicalType,
+		precision:   opts.precision,
+		scale:       opts.scale,
+		size:        opts.size,
+		backing:     opts.backing,
+	}
+}
+
+// generatedName returns the name of an anonymous fixed of the given kind, such as fixed or decimal, made of its
+// scope, as in Artifact_salt_fixed. The names are the same from one inference to the next, and the fixeds of
+// different fields don't share a name even when they have the same size.
+func generatedName(opts tagOptions, kind string) string {
+	if opts.scope == "" {
+		return kind
+	}
+
+	return opts.scope + "_" + kind
+}
+
+// inferUUID fills s with a uuid logical type backed by a string, or by a fixed for a [16]byte.
+func inferUUID(s *TypedSchema, t reflect.Type, opts tagOptions) error {
+	s.LogicalType = "uuid"
+
+	switch {
+	case t.Kind() == reflect.String:
+		s.Type = "string"
+
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && t.Len() == 16:
+		s.Type = "fixed"
+		s.Size = 16
+		s.Namespace = opts.namespace
+
+		s.Name = t.Name()
+		if opts.name != "" {
+			s.Name = opts.name
+		} else if s.Name == "" {
+			s.Name = generatedName(opts, "uuid")
+		}
+
+	default:
+		return fmt.Errorf("uuid must be string based or a [16]byte, got %s", t)
+	}
+
+	return nil
+}
+
+// inferEnum fills s with an enum of the given symbols.
+func inferEnum(s *TypedSchema, t reflect.Type, opts tagOptions) error {
+	if t.Kind() != reflect.String {
+		return fmt.Errorf("enum must be string based, got %s", t.Kind())
+	}
+
+	for _, symbol := range opts.symbols {
+		if !avroNameRegexp.MatchString(symbol) {
+			return fmt.Errorf("invalid enum symbol: %q", symbol)
+		}
+	}
+
+	s.Type = "enum"
+	s.Symbols = opts.symbols
+
+	if opts.defaultVal != nil && !isSymbol(opts.symbols, *opts.defaultVal) {
+		return fmt.Errorf("enum default %q is not one of its symbols", *opts.defaultVal)
+	}
+
+	if opts.enumDefault != "" {
+		if !isSymbol(opts.symbols, opts.enumDefault) {
+			return fmt.Errorf("enum default symbol %q is not one of its symbols", opts.enumDefault)
+		}
+
+		s.Default = opts.enumDefault
+	}
+
+	return nil
+}
+
+// inferType returns the avro primitive type of a Go basic kind.
+//
+// Avro int is 32-bit and long is 64-bit, so integers are mapped to the smallest type holding all their values.
+// int and uint have the platform word size: int is an avro int on 32-bit platforms and a long on 64-bit ones,
+// uint is always a long. Note that uint64 values above math.MaxInt64 overflow the avro long.
 func inferType(t reflect.Type) (string, error) {
 	switch t.Kind() {
 	case reflect.String:
 		return "string", nil
 	case reflect.Bool:
 		return "boolean", nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
 		return "int", nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int:
+		if strconv.IntSize == 32 {
+			return "int", nil
+		}
 		return "long", nil
-	case reflect.Float32, reflect.Float64:
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "long", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
 		return "double", nil
 	}
 
+	switch t.Kind() {
+	case reflect.Complex64, reflect.Complex128, reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Uintptr:
+		return "", fmt.Errorf("unsupported type: %s has no avro equivalent, "+
+			`set the type with the type=, items= or values= tag option, or skip the field with avro:"-"`, t.Kind())
+	}
+
 	return "", fmt.Errorf("unsupported type: %s", t.Kind())
 }
 
-func inferSchema(fallbackTag string, t reflect.Type, items, values []string) (s TypedSchema, err error) {
-	s.Name = t.Name()
+// definition is a named type defined during an inference, along with the struct defining it for a record.
+type definition struct {
+	t      reflect.Type
+	schema TypedSchema
+}
 
-	switch t.Kind() {
-	case reflect.Ptr:
-		typ, err := inferSchema(fallbackTag, t.Elem(), nil, nil)
+// inferrer holds the state of a schema inference.
+type inferrer struct {
+	inferOptions
+	// named holds the definitions of the named types already defined, by full name
+	named map[string]definition
+	// visiting holds the types being inferred, to detect recursion
+	visiting map[reflect.Type]bool
+	// ctx aborts the inference once done, if set
+	ctx context.Context
+}
+
+// canceled returns the error of the context of the inference once it is done.
+func (in *inferrer) canceled() error {
+	if in.ctx == nil {
+		return nil
+	}
+
+	return in.ctx.Err()
+}
+
+func (in *inferrer) inferSchema(t reflect.Type, opts tagOptions) (s TypedSchema, err error) {
+	if err := in.canceled(); err != nil {
+		return s, err
+	}
+
+	if fn, ok := registeredType(t); ok {
+		s = fn()
+		if union, ok := s.Type.([]interface{}); ok {
+			if err := checkUnion(union); err != nil {
+				return s, fmt.Errorf("registered schema of %s: %w", t, err)
+			}
+		}
+
+		if isNamed(s) {
+			if err := checkNames(s); err != nil {
+				return s, err
+			}
+
+			if ref, ok, err := in.reference(s, nil); ok || err != nil {
+				return ref, err
+			}
+		}
+
+		return s, nil
+	}
+
+	if fields, ok := registeredOptional(t); ok {
+		if err := fields.check(t); err != nil {
+			return s, err
+		}
+
+		value, _ := t.FieldByName(fields.value)
+
+		typ, err := in.inferSchema(value.Type, opts)
 		if err != nil {
-			return s, fmt.Errorf("ptr: %w", err)
+			return s, err
 		}
 
-		s.types = append(s.types, typ)
-		s.types = append(s.types, "null")
+		s.Type = in.nullable(typ)
 
-	case reflect.Struct:
-		s.types = append(s.types, "record")
-		s.Fields = make([]TypedSchema, t.NumField())
+		return s, nil
+	}
 
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
+	// an interface with registered implementations is the union of their records, which it can recurse through
+	if impls, ok := registeredUnion(t); ok {
+		union := make([]interface{}, len(impls))
+		for i, impl := range impls {
+			if impl.Kind() == reflect.Ptr {
+				impl = impl.Elem()
+			}
 
-			tags, err := structtag.Parse(string(field.Tag))
+			typ, err := in.inferSchema(impl, tagOptions{namespace: opts.namespace})
 			if err != nil {
-				return s, fmt.Errorf("struct: %w", err)
+				return s, fmt.Errorf("implementation %s of %s: %w", impl, t, err)
 			}
 
-			var (
-				name        string
-				fieldValues []string
-				fieldItems  []string
-			)
+			union[i] = typ.schema()
+		}
 
-			if tag, err := tags.Get("avro"); err == nil {
-				name = tag.Name
+		if err := checkUnion(union); err != nil {
+			return s, fmt.Errorf("union of %s: %w", t, err)
+		}
 
-				for _, opt := range tag.Options {
-					if strings.HasPrefix(opt, "type=") {
-						typeStr := strings.TrimPrefix(opt, "type=")
-						types := strings.Split(typeStr, "|")
+		s.Type = union
 
-						for _, t := range types {
-							s.Fields[i].types = append(s.Fields[i].types, t)
-						}
-					}
-				}
+		return s, nil
+	}
 
-				for _, opt := range tag.Options {
-					if strings.HasPrefix(opt, "values=") {
-						valuesStr := strings.TrimPrefix(opt, "values=")
-						fieldValues = strings.Split(valuesStr, "|")
-					}
-				}
+	// recursive records are referenced by name, any other recursion can't be represented
+	if t.Kind() != reflect.Struct {
+		if in.visiting[t] {
+			return s, fmt.Errorf("recursive type %s must go through a struct", t)
+		}
 
-				for _, opt := range tag.Options {
-					if strings.HasPrefix(opt, "items=") {
-						itemsStr := strings.TrimPrefix(opt, "items=")
-						fieldItems = strings.Split(itemsStr, "|")
-					}
-				}
-			} else if tag, err := tags.Get(fallbackTag); err == nil {
-				name = tag.Name
-			} else {
-				name = field.Name
+		in.visiting[t] = true
+		defer delete(in.visiting, t)
+	}
+
+	switch {
+	case t == timeType:
+		if s, err = timeSchema(opts.logicalType); err != nil {
+			return s, err
+		}
+
+	// the date and time of day types which aren't registered are selected by their logical type
+	case isCalendarType(opts.logicalType) && t.Kind() != reflect.Ptr && t.Implements(textMarshalerType):
+		if s, err = timeSchema(opts.logicalType); err != nil {
+			return s, err
+		}
+
+	// a big.Int is a long unless it is tagged as a decimal, the values which don't fit failing to encode
+	case t == bigIntType && opts.precision == "" && opts.logicalType == "" && opts.backing == "":
+		s.Type = "long"
+
+	case t == bigRatType, t == bigIntType:
+		err = inferDecimal(&s, opts)
+		if err != nil {
+			return s, err
+		}
+
+		if s.Type == "fixed" {
+			s.Name = generatedName(opts, "decimal")
+			if opts.name != "" {
+				s.Name = opts.name
 			}
 
-			if s.Fields[i].types == nil {
-				s.Fields[i], err = inferSchema(fallbackTag, field.Type, fieldItems, fieldValues)
-				if err != nil {
-					return s, fmt.Errorf("struct: %w", err)
-				}
-			} else if len(s.Fields[i].types) == 1 {
-				s.Fields[i].Type = s.Fields[i].types[0]
-			} else {
-				s.Fields[i].Type = s.Fields[i].types
+			s.Namespace = opts.namespace
+		}
+
+	case t.Kind() == reflect.Ptr:
+		typ, err := in.inferSchema(t.Elem(), opts)
+		if err != nil {
+			return s, err
+		}
+
+		s.Type = in.nullable(typ)
+
+	case opts.logicalType == "uuid":
+		err = inferUUID(&s, t, opts)
+		if err != nil {
+			return s, err
+		}
+
+	case opts.symbols != nil:
+		err = inferEnum(&s, t, opts)
+		if err != nil {
+			return s, err
+		}
+
+		s.Name = opts.fieldName
+		if opts.name != "" {
+			s.Name = opts.name
+		} else if t.PkgPath() != "" {
+			s.Name = t.Name()
+		}
+
+		s.Namespace = opts.namespace
+
+	case t.Kind() == reflect.Struct:
+		s.Type = "record"
+		s.Name = t.Name()
+		if opts.name != "" {
+			s.Name = opts.name
+		}
+
+		if s.Name == "" && opts.fieldName == "" {
+			return s, errors.New("anonymous struct has no record name, set one with WithRecordName")
+		}
+
+		s.Namespace = opts.namespace
+
+		// an anonymous struct is named after its field, as in Geo for geo struct{...}
+		if s.Name == "" {
+			s.Name = exportedName(opts.fieldName)
+			if _, ok := in.named[AddNamespace(s.Namespace, s.Name)]; ok {
+				return s, fmt.Errorf("anonymous struct of %s is named %s, which is already defined, set another name with the name= tag option",
+					opts.fieldName, s.Name)
 			}
+		}
+
+		if err := in.recordOptions(&s, t); err != nil {
+			return s, err
+		}
+
+		// the name= option of the field overrides the one of the blank field
+		if opts.name != "" {
+			s.Name = opts.name
+		}
+
+		if err := checkNames(s); err != nil {
+			return s, err
+		}
+
+		if ref, ok, err := in.reference(s, t); ok || err != nil {
+			return ref, err
+		}
+
+		fields, err := in.inferFields(t, s.Name, s.Namespace, 0)
+		if err != nil {
+			return s, err
+		}
+
+		s.Fields, err = promoteFields(AddNamespace(s.Namespace, s.Name), fields)
+		if err != nil {
+			return s, err
+		}
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s.Type = "bytes"
 
-			s.Fields[i].Name = name
+	case t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8:
+		s.Type = "fixed"
+		s.Name = t.Name()
+		if opts.name != "" {
+			s.Name = opts.name
+		} else if s.Name == "" {
+			s.Name = generatedName(opts, "fixed")
 		}
 
-	case reflect.Slice:
-		s.types = append(s.types, "array")
+		s.Namespace = opts.namespace
+		s.Size = t.Len()
+
+	// avro has no fixed-length arrays, the other Go arrays are arrays of their elements
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		s.Type = "array"
+
+		if opts.items != nil {
+			if s.Items, err = typeNames(opts.items); err != nil {
+				return s, fmt.Errorf("items: %w", err)
+			}
 
-		if items != nil {
-			for _, i := range items {
-				s.items = append(s.items, i)
+			if err := in.checkTypes(s.Items, opts.namespace); err != nil {
+				return s, fmt.Errorf("items: %w", err)
 			}
 		} else {
-			typ, err := inferSchema(fallbackTag, t.Elem(), nil, nil)
+			typ, err := in.inferSchema(t.Elem(), opts.elementOptions("items"))
 			if err != nil {
-				return s, fmt.Errorf("slice: %w", err)
+				return s, prependPath("[]", err)
 			}
 
-			s.items = append(s.items, typ)
+			s.Items = typ.schema()
 		}
 
-	case reflect.Map:
-		s.types = append(s.types, "map")
+	case t.Kind() == reflect.Map:
+		s.Type = "map"
 
-		if t.Key().Kind() != reflect.String {
+		switch {
+		case t.Key().Kind() == reflect.String:
+		case !in.stringifyMapKeys:
 			return s, errors.New("map key must be string")
+		case !isMapKey(t.Key()):
+			return s, fmt.Errorf("map key must be a string, an integer or a fmt.Stringer, got %s", t.Key())
 		}
 
-		if values != nil {
-			for _, v := range values {
-				s.values = append(s.values, v)
+		if opts.values != nil {
+			if s.Values, err = typeNames(opts.values); err != nil {
+				return s, fmt.Errorf("values: %w", err)
+			}
+
+			if err := in.checkTypes(s.Values, opts.namespace); err != nil {
+				return s, fmt.Errorf("values: %w", err)
 			}
 		} else {
-			typ, err := inferSchema(fallbackTag, t.Elem(), nil, nil)
+			typ, err := in.inferSchema(t.Elem(), opts.elementOptions("values"))
 			if err != nil {
-				return s, fmt.Errorf("map: %w", err)
+				return s, prependPath("[]", err)
 			}
 
-			s.values = append(s.values, typ)
+			s.Values = typ.schema()
 		}
 
+	// an error is written as its message, nil being null
+	case t == errorType && !in.strictErrors:
+		s.Type = in.nullable(TypedSchema{Type: "string"})
+
+	case t.Kind() == reflect.Interface:
+		return s, errors.New("interface has no structural type, an explicit union is required such as type=string|int|null")
+
 	default:
-		typ, err := inferType(t)
+		s.Type, err = inferType(t)
 		if err != nil {
-			return s, fmt.Errorf("default: %w", err)
+			return s, err
+		}
+	}
+
+	if s.Type == "enum" || s.Type == "fixed" {
+		if err := checkNames(s); err != nil {
+			return s, err
 		}
 
-		s.types = append(s.types, typ)
+		if ref, ok, err := in.reference(s, nil); ok || err != nil {
+			return ref, err
+		}
 	}
 
-	if len(s.types) == 1 {
-		s.Type = s.types[0]
-	} else if len(s.types) > 1 {
-		s.Type = s.types
+	return s, nil
+}
+
+// nullable returns the union of null and typ, null being first unless WithNullLast is set.
+// Unions can't be nested, so null is added to the branches of a union, such as the one of a pointer to a pointer.
+func (in *inferrer) nullable(typ TypedSchema) []interface{} {
+	branches, ok := typ.schema().([]interface{})
+	if !ok {
+		branches = []interface{}{typ.schema()}
 	}
 
-	if len(s.items) == 1 {
-		s.Items = s.items[0]
-	} else if len(s.items) > 1 {
-		s.Items = s.items
+	for i, branch := range branches {
+		if branch == "null" {
+			branches = append(branches[:i:i], branches[i+1:]...)
+			break
+		}
 	}
 
-	if len(s.values) == 1 {
-		s.Values = s.values[0]
-	} else if len(s.values) > 1 {
-		s.Values = s.values
+	if in.nullLast {
+		return append(branches, "null")
 	}
 
-	return s, nil
+	return append([]interface{}{"null"}, branches...)
 }
 
-// InferSchema will infer the avro schema from a Go struct.
-// The fallbackTag parameter is the name of the struct tag to use if the avro tag is not present.
-// The v parameter is the struct to infer the schema from.
-func InferSchema(fallbackTag string, v interface{}) (string, error) {
-	s, err := inferSchema(fallbackTag, reflect.TypeOf(v), nil, nil)
+// isNullFirst tells if s is a union whose first branch is null, which makes null a valid default.
+func isNullFirst(s TypedSchema) bool {
+	union, ok := s.Type.([]interface{})
+
+	return ok && len(union) > 0 && union[0] == "null"
+}
+
+// isNamed tells if s defines a named type, which can be referenced by its full name.
+func isNamed(s TypedSchema) bool {
+	switch s.Type {
+	case "record", "enum", "fixed":
+		return s.Name != ""
+	}
+
+	return false
+}
+
+// structField is a record field along with the embedding depth of the struct field it comes from.
+type structField struct {
+	schema TypedSchema
+	depth  int
+	// tagged tells if the name of the field is set by a tag
+	tagged bool
+}
+
+// inferFields infers the record fields of the struct t, including the ones promoted from its embedded structs.
+// With WithCollectErrors, the errors of all the fields are returned together.
+func (in *inferrer) inferFields(t reflect.Type, record, namespace string, depth int) ([]structField, error) {
+	var (
+		fields []structField
+		errs   inferErrors
+	)
+
+	for i := 0; i < t.NumField(); i++ {
+		inferred, err := in.inferField(t.Field(i), record, namespace, depth)
+		if err != nil {
+			// the fields left aren't inferred once the context is done, even when collecting the errors
+			if !in.collectErrors || in.canceled() != nil {
+				return nil, err
+			}
+
+			errs = errs.append(err)
+
+			continue
+		}
+
+		fields = append(fields, inferred...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return fields, nil
+}
+
+// inferField infers the field of the record, or the fields it promotes if it is an embedded struct.
+func (in *inferrer) inferField(field reflect.StructField, record, namespace string, depth int) ([]structField, error) {
+	embedded := field.Type
+	if embedded.Kind() == reflect.Ptr {
+		embedded = embedded.Elem()
+	}
+
+	// embedded structs with a registered mapping are regular fields
+	_, registered := registeredType(embedded)
+	isEmbeddedStruct := field.Anonymous && embedded.Kind() == reflect.Struct && !registered
+
+	// unexported fields can't be marshaled, so they aren't part of the schema,
+	// but the exported fields of unexported embedded structs are still promoted
+	if field.PkgPath != "" && !isEmbeddedStruct {
+		return nil, nil
+	}
+
+	name, tagged, fieldOpts, err := in.fieldTag(field)
 	if err != nil {
-		return "", fmt.Errorf("infer schema: %w", err)
+		return nil, prependPath(field.Name, err)
+	}
+
+	// "-" on the avro or fallback tag omits the field, as with encoding/json
+	if name == "-" {
+		return nil, nil
+	}
+
+	// the fields of untagged embedded structs are promoted to the enclosing record, as with encoding/json
+	if isEmbeddedStruct && !tagged {
+		if in.visiting[embedded] {
+			return nil, nil
+		}
+
+		in.visiting[embedded] = true
+		promoted, err := in.inferFields(embedded, record, namespace, depth+1)
+		delete(in.visiting, embedded)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return promoted, nil
+	}
+
+	if field.PkgPath != "" {
+		return nil, nil
+	}
+
+	if !avroNameRegexp.MatchString(name) {
+		return nil, prependPath(field.Name, fmt.Errorf("invalid field name %q, must match %s", name, avroNameRegexp))
+	}
+
+	fieldOpts.namespace = namespaceOr(fieldOpts.namespace, namespace)
+	for _, alias := range fieldOpts.aliases {
+		if !avroNameRegexp.MatchString(alias) {
+			return nil, prependPath(field.Name, fmt.Errorf("invalid alias %q of %s", alias, name))
+		}
+	}
+
+	if fieldOpts.name != "" && !avroNameRegexp.MatchString(fieldOpts.name) {
+		return nil, prependPath(field.Name, fmt.Errorf("invalid type name %q of %s", fieldOpts.name, name))
+	}
+
+	switch fieldOpts.order {
+	case "", "ascending", "descending", "ignore":
+	default:
+		return nil, prependPath(field.Name, fmt.Errorf("invalid order %q of %s, must be one of ascending, descending or ignore", fieldOpts.order, name))
+	}
+
+	fieldOpts.fieldName = name
+	fieldOpts.scope = record + "_" + name
+	f := TypedSchema{Name: name, Doc: fieldOpts.doc, Aliases: fieldOpts.aliases, Order: fieldOpts.order, Props: in.flagProps(fieldOpts)}
+
+	var typ TypedSchema
+
+	// a reference to a named type defined elsewhere is written as is, nullable if the field is a pointer
+	if fieldOpts.ref != "" {
+		if fieldOpts.types != nil || fieldOpts.schema != nil {
+			return nil, prependPath(field.Name, fmt.Errorf("ref of %s is exclusive with schema and type", name))
+		}
+
+		if !isFullName(fieldOpts.ref) || isPrimitive(fieldOpts.ref) {
+			return nil, prependPath(field.Name, fmt.Errorf("invalid ref %q of %s, must be the full name of a named type", fieldOpts.ref, name))
+		}
+
+		typ.Type = fieldOpts.ref
+		if field.Type.Kind() == reflect.Ptr {
+			typ.Type = in.nullable(typ)
+		}
+
+		if isNullFirst(typ) {
+			f.Default = Null{}
+		}
+
+		if fieldOpts.defaultVal != nil {
+			// the default is written as is, the referenced type not being known
+			f.Default = rawDefault(*fieldOpts.defaultVal)
+		}
+
+		f.Type = typ.schema()
+
+		return []structField{{schema: f, depth: depth, tagged: tagged}}, nil
+	}
+
+	if fieldOpts.schema != nil {
+		if fieldOpts.types != nil {
+			return nil, prependPath(field.Name, fmt.Errorf("schema and type of %s are exclusive", name))
+		}
+
+		f.Type = fieldOpts.schema
+		if fieldOpts.defaultVal != nil {
+			// the default is written as is, the schema not being modeled
+			f.Default = rawDefault(*fieldOpts.defaultVal)
+		}
+
+		return []structField{{schema: f, depth: depth, tagged: tagged}}, nil
+	}
+
+	if fieldOpts.types == nil {
+		typ, err = in.inferSchema(field.Type, fieldOpts)
+		if err != nil {
+			return nil, prependPath(field.Name, err)
+		}
+
+		// an unset oneof is nil
+		if (fieldOpts.omitEmpty && in.omitEmptyNullable) || isOneof(field) {
+			typ = TypedSchema{Type: in.nullable(typ)}
+		}
+
+		if isNullFirst(typ) {
+			f.Default = Null{}
+		}
+	} else if typ.Type, err = typeNames(fieldOpts.types); err != nil {
+		return nil, prependPath(field.Name, fmt.Errorf("type of %s: %w", name, err))
+	}
+
+	if fieldOpts.defaultVal != nil {
+		f.Default, err = parseDefault(typ, *fieldOpts.defaultVal)
+		if err != nil {
+			return nil, prependPath(field.Name, fmt.Errorf("default of %s: %w", name, err))
+		}
+	}
+
+	if f.Default == nil && in.zeroDefaults {
+		f.Default = zeroDefault(field.Type, typ)
+	}
+
+	f.Type = typ.schema()
+
+	return []structField{{schema: f, depth: depth, tagged: tagged}}, nil
+}
+
+// zeroDefault returns the zero value of the Go type t as the default of a field of the type typ, for the booleans,
+// numbers and strings without logical type, or nil.
+func zeroDefault(t reflect.Type, typ TypedSchema) interface{} {
+	if typ.LogicalType != "" {
+		return nil
+	}
+
+	zero := reflect.Zero(t)
+
+	switch typ.Type {
+	case "boolean":
+		if t.Kind() == reflect.Bool {
+			return false
+		}
+
+	case "int", "long":
+		if isInteger(zero) {
+			return int64(0)
+		}
+
+	case "float", "double":
+		if t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64 {
+			return float64(0)
+		}
+
+	case "string":
+		if t.Kind() == reflect.String {
+			return ""
+		}
+	}
+
+	return nil
+}
+
+// recordOptions sets the name, namespace, doc, aliases and custom attributes of the record s of the struct t from the tag of its blank field,
+// as in _ struct{} `avro:",namespace=com.acme,aliases=Client|Buyer"`, the namespace overriding the inherited one.
+func (in *inferrer) recordOptions(s *TypedSchema, t reflect.Type) error {
+	var field *reflect.StructField
+	for i := 0; i < t.NumField() && field == nil; i++ {
+		if f := t.Field(i); f.Name == "_" {
+			field = &f
+		}
+	}
+
+	if field == nil {
+		return nil
+	}
+
+	_, _, opts, err := in.fieldTag(*field)
+	if err != nil {
+		return fmt.Errorf("record options: %w", err)
+	}
+
+	for _, alias := range opts.aliases {
+		if !isFullName(alias) {
+			return fmt.Errorf("invalid alias %q of record %s", alias, s.Name)
+		}
+	}
+
+	if opts.name != "" {
+		s.Name = opts.name
+	}
+
+	s.Namespace = namespaceOr(opts.namespace, s.Namespace)
+	s.Doc = opts.doc
+	s.Aliases = opts.aliases
+	s.Props = in.flagProps(opts)
+
+	return nil
+}
+
+// flagProps returns the custom attributes set by the tag options, those of the pii and deprecated flags included.
+func (in *inferrer) flagProps(opts tagOptions) map[string]interface{} {
+	if !opts.pii && !opts.deprecated {
+		return opts.props
+	}
+
+	props := make(map[string]interface{}, len(opts.props)+2)
+	for key, v := range opts.props {
+		props[key] = v
+	}
+
+	pii, deprecated := "x-pii", "x-deprecated"
+	if in.piiProp != "" {
+		pii = in.piiProp
+	}
+
+	if in.deprecatedProp != "" {
+		deprecated = in.deprecatedProp
+	}
+
+	if opts.pii {
+		props[pii] = true
+	}
+
+	if opts.deprecated {
+		props[deprecated] = true
+	}
+
+	return props
+}
+
+// promoteFields resolves the name collisions between fields and promoted fields of the record name as encoding/json
+// does, see dominantField. Two fields declared on the struct itself with the same name are an error.
+func promoteFields(name string, fields []structField) ([]TypedSchema, error) {
+	byName := make(map[string][]int)
+	for i, f := range fields {
+		if others := byName[f.schema.Name]; len(others) > 0 && f.depth == 0 && fields[others[0]].depth == 0 {
+			return nil, fmt.Errorf("duplicate field name %q in record %s", f.schema.Name, name)
+		}
+
+		byName[f.schema.Name] = append(byName[f.schema.Name], i)
+	}
+
+	winners := make(map[int]bool, len(byName))
+	for _, candidates := range byName {
+		depths, tagged := make([]int, len(candidates)), make([]bool, len(candidates))
+		for j, i := range candidates {
+			depths[j], tagged[j] = fields[i].depth, fields[i].tagged
+		}
+
+		if w, ok := dominantField(depths, tagged); ok {
+			winners[candidates[w]] = true
+		}
+	}
+
+	var schemas []TypedSchema
+	for i, f := range fields {
+		if winners[i] {
+			schemas = append(schemas, f.schema)
+		}
+	}
+
+	return schemas, nil
+}
+
+// dominantField returns which of the fields of the same name, given their depth and whether their name is tagged,
+// is kept as encoding/json does: the least nested one, or the only tagged one among the least nested. The fields
+// left tied are all dropped, which is told by false.
+func dominantField(depths []int, tagged []bool) (int, bool) {
+	min := depths[0]
+	for _, d := range depths {
+		if d < min {
+			min = d
+		}
+	}
+
+	var least, taggedLeast []int
+	for i, d := range depths {
+		if d != min {
+			continue
+		}
+
+		least = append(least, i)
+		if tagged[i] {
+			taggedLeast = append(taggedLeast, i)
+		}
+	}
+
+	switch {
+	case len(least) == 1:
+		return least[0], true
+	case len(taggedLeast) == 1:
+		return taggedLeast[0], true
+	}
+
+	return 0, false
+}
+
+// fieldIndexes returns the index sequences of the struct fields of t by record field name,
+// following the same rules as inferFields to name, skip and promote the fields.
+func (in *inferrer) fieldIndexes(t reflect.Type) (map[string][]int, error) {
+	candidates := make(map[string][][]int)
+	taggedNames := make(map[string][]bool)
+
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+
+			_, registered := registeredType(embedded)
+			isEmbeddedStruct := field.Anonymous && embedded.Kind() == reflect.Struct && !registered
+
+			if field.PkgPath != "" && !isEmbeddedStruct {
+				continue
+			}
+
+			name, tagged, _, err := in.fieldTag(field)
+			if err != nil {
+				return err
+			}
+
+			if name == "-" {
+				continue
+			}
+
+			if isEmbeddedStruct && !tagged {
+				if in.visiting[embedded] {
+					continue
+				}
+
+				in.visiting[embedded] = true
+				err := walk(embedded, fieldIndex)
+				delete(in.visiting, embedded)
+
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if field.PkgPath != "" {
+				continue
+			}
+
+			candidates[name] = append(candidates[name], fieldIndex)
+			taggedNames[name] = append(taggedNames[name], tagged)
+		}
+
+		return nil
+	}
+
+	if err := walk(t, nil); err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string][]int, len(candidates))
+	for name, fieldIndexes := range candidates {
+		depths := make([]int, len(fieldIndexes))
+		for i, index := range fieldIndexes {
+			depths[i] = len(index)
+		}
+
+		if w, ok := dominantField(depths, taggedNames[name]); ok {
+			indexes[name] = fieldIndexes[w]
+		}
+	}
+
+	return indexes, nil
+}
+
+// parseDefault parses the default value of a field of the type typ into its JSON value.
+// The default value of a union is a value of its first branch.
+func parseDefault(typ interface{}, raw string) (interface{}, error) {
+	switch typ := typ.(type) {
+	case []interface{}:
+		return parseDefault(typ[0], raw)
+
+	case TypedSchema:
+		switch typ.Type {
+		case "enum":
+			if !isSymbol(typ.Symbols, raw) {
+				return nil, fmt.Errorf("%q is not a symbol of the enum", raw)
+			}
+
+			return raw, nil
+
+		case "fixed":
+			if len(raw) != typ.Size {
+				return nil, fmt.Errorf("%q doesn't have the size %d of the fixed", raw, typ.Size)
+			}
+
+			return raw, nil
+		}
+
+		return parseDefault(typ.Type, raw)
+
+	case string:
+		switch typ {
+		case "null":
+			if raw != "null" {
+				return nil, fmt.Errorf("%q isn't null", raw)
+			}
+
+			return Null{}, nil
+
+		case "boolean":
+			return strconv.ParseBool(raw)
+
+		case "int":
+			return strconv.ParseInt(raw, 10, 32)
+
+		case "long":
+			return strconv.ParseInt(raw, 10, 64)
+
+		case "float", "double":
+			return strconv.ParseFloat(raw, 64)
+
+		case "string", "bytes":
+			return raw, nil
+
+		case "array", "map", "record":
+			var v interface{}
+			if err := json.Unmarshal([]byte(raw), &v); err != nil {
+				return nil, fmt.Errorf("%q isn't a JSON %s: %w", raw, typ, err)
+			}
+
+			_, isArray := v.([]interface{})
+			_, isObject := v.(map[string]interface{})
+
+			if (typ == "array" && !isArray) || (typ != "array" && !isObject) {
+				return nil, fmt.Errorf("%q isn't a JSON %s", raw, typ)
+			}
+
+			return v, nil
+		}
+
+		// a reference to a named type defined elsewhere: accept JSON values, or strings for enums and fixed
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return raw, nil
+		}
+
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unexpected type %v", typ)
+}
+
+// rawDefault returns the JSON value of the default raw, or raw as a string if it isn't JSON.
+func rawDefault(raw string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+
+	if v == nil {
+		return Null{}
+	}
+
+	return v
+}
+
+// isSymbol reports whether symbol is one of the symbols of an enum.
+func isSymbol(symbols []string, symbol string) bool {
+	for _, s := range symbols {
+		if s == symbol {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fieldTag returns the avro name of a struct field and the options of its avro tag, or of the tag set by WithTagKey.
+// The name is tagged when it is set by the avro or fallback tag rather than taken from the field.
+func (in *inferrer) fieldTag(field reflect.StructField) (name string, tagged bool, opts tagOptions, err error) {
+	tags, err := structtag.Parse(string(field.Tag))
+	if err != nil {
+		return "", false, opts, err
+	}
+
+	key := in.tagKey
+	if key == "" {
+		key = "avro"
+	}
+
+	if tag, err := tags.Get(key); err == nil {
+		name = tag.Name
+		tag.Options = joinQuoted(tag.Options)
+
+		for _, opt := range tag.Options {
+			if strings.HasPrefix(opt, "type=") {
+				typeStr := strings.TrimPrefix(opt, "type=")
+				opts.types = splitUnion(typeStr)
+			}
+		}
+
+		for _, opt := range tag.Options {
+			if strings.HasPrefix(opt, "values=") {
+				valuesStr := strings.TrimPrefix(opt, "values=")
+				opts.values = splitUnion(valuesStr)
+			}
+		}
+
+		for _, opt := range tag.Options {
+			if strings.HasPrefix(opt, "items=") {
+				itemsStr := strings.TrimPrefix(opt, "items=")
+				opts.items = splitUnion(itemsStr)
+			}
+		}
+
+		for _, opt := range tag.Options {
+			switch {
+			case strings.HasPrefix(opt, "logicalType="):
+				opts.logicalType = strings.TrimPrefix(opt, "logicalType=")
+			case strings.HasPrefix(opt, "precision="):
+				opts.precision = strings.TrimPrefix(opt, "precision=")
+			case strings.HasPrefix(opt, "scale="):
+				opts.scale = strings.TrimPrefix(opt, "scale=")
+			case strings.HasPrefix(opt, "size="):
+				opts.size = strings.TrimPrefix(opt, "size=")
+			case strings.HasPrefix(opt, "backing="):
+				opts.backing = strings.TrimPrefix(opt, "backing=")
+			case strings.HasPrefix(opt, "enum="):
+				opts.symbols = strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+			case strings.HasPrefix(opt, "enumdefault="):
+				opts.enumDefault = strings.TrimPrefix(opt, "enumdefault=")
+			case strings.HasPrefix(opt, "default="):
+				defaultVal := strings.TrimPrefix(opt, "default=")
+				opts.defaultVal = &defaultVal
+			case strings.HasPrefix(opt, "namespace="):
+				opts.namespace = strings.TrimPrefix(opt, "namespace=")
+			case strings.HasPrefix(opt, "doc="):
+				opts.doc = strings.TrimPrefix(opt, "doc=")
+			case strings.HasPrefix(opt, "order="):
+				opts.order = strings.TrimPrefix(opt, "order=")
+			case strings.HasPrefix(opt, "name="):
+				opts.name = strings.TrimPrefix(opt, "name=")
+			case strings.HasPrefix(opt, "aliases="):
+				opts.aliases = strings.Split(strings.TrimPrefix(opt, "aliases="), "|")
+			case opt == "pii":
+				opts.pii = true
+			case opt == "deprecated":
+				opts.deprecated = true
+			case strings.HasPrefix(opt, "props="):
+				if opts.props, err = propsOption(strings.TrimPrefix(opt, "props=")); err != nil {
+					return "", false, opts, err
+				}
+			case strings.HasPrefix(opt, "ref="):
+				opts.ref = strings.TrimPrefix(opt, "ref=")
+			case strings.HasPrefix(opt, "schema="):
+				if opts.schema, err = schemaOption(strings.TrimPrefix(opt, "schema=")); err != nil {
+					return "", false, opts, err
+				}
+			}
+		}
+	} else {
+		// the first fallback tag naming the field wins
+		for _, fallback := range in.fallbackTags {
+			if tag, err := tags.Get(fallback); err == nil && tag.Name != "" {
+				name = tag.Name
+				break
+			}
+		}
+
+		for _, fallback := range in.fallbackTags {
+			if tag, err := tags.Get(fallback); err == nil && tag.HasOption("omitempty") {
+				opts.omitEmpty = true
+			}
+		}
+
+		// the fields of the protobuf generated structs are named by their protobuf tag
+		if name == "" {
+			name = protobufName(field)
+		}
+
+		if isProtobufInternal(field) {
+			name = "-"
+		}
+	}
+
+	// the avrodoc tag holds docs which can't be written as an option, such as the ones containing commas
+	if doc, ok := field.Tag.Lookup("avrodoc"); ok {
+		opts.doc = doc
+	}
+
+	if name != "" {
+		return name, true, opts, nil
+	}
+
+	if in.nameMapper != nil {
+		return in.nameMapper(field.Name), false, opts, nil
+	}
+
+	return field.Name, false, opts, nil
+}
+
+// joinQuoted joins back the tag options split on the commas of a single-quoted value, as in schema='{"a":1,"b":2}'.
+func joinQuoted(options []string) []string {
+	joined := make([]string, 0, len(options))
+
+	for i := 0; i < len(options); i++ {
+		opt := options[i]
+
+		if eq := strings.Index(opt, "='"); eq >= 0 {
+			for (len(opt) == eq+2 || !strings.HasSuffix(opt, "'")) && i+1 < len(options) {
+				i++
+				opt += "," + options[i]
+			}
+		}
+
+		joined = append(joined, opt)
+	}
+
+	return joined
+}
+
+// schemaOption parses the value of the schema= tag option: a single-quoted or base64 encoded JSON schema.
+func schemaOption(value string) (json.RawMessage, error) {
+	var raw []byte
+
+	if strings.HasPrefix(value, "'") {
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("unterminated schema %s", value)
+		}
+
+		raw = []byte(value[1 : len(value)-1])
+	} else {
+		var err error
+		if raw, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("schema must be single-quoted or base64 encoded JSON: %w", err)
+		}
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("schema %s isn't well-formed JSON: %w", raw, err)
+	}
+
+	switch v := v.(type) {
+	case string, []interface{}:
+	case map[string]interface{}:
+		if _, ok := v["type"]; !ok {
+			return nil, fmt.Errorf("schema %s without a type", raw)
+		}
+	default:
+		return nil, fmt.Errorf("schema %s must be a type name, a union or an object", raw)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, err
+	}
+
+	return compact.Bytes(), nil
+}
+
+// propsOption parses the value of the props= tag option, as in props=x-team:payments|x-pii:true.
+// The values which are JSON, such as true or 1, are written as such, the other ones as strings.
+func propsOption(value string) (map[string]interface{}, error) {
+	props := make(map[string]interface{})
+
+	for _, prop := range strings.Split(value, "|") {
+		i := strings.Index(prop, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("prop %q must be written as key:value", prop)
+		}
+
+		key := prop[:i]
+		if reservedAttributes[key] {
+			return nil, fmt.Errorf("prop %s is a standard attribute", key)
+		}
+
+		props[key] = rawDefault(prop[i+1:])
+	}
+
+	return props, nil
+}
+
+// checkNames checks that the name and namespace of the named type s are valid avro names,
+// the namespace being validated component by component.
+func checkNames(s TypedSchema) error {
+	if !isFullName(s.Name) {
+		return fmt.Errorf("invalid %s name %q, must match %s", s.Type, s.Name, avroNameRegexp)
+	}
+
+	if s.Namespace != "" && !isFullName(s.Namespace) {
+		return fmt.Errorf("invalid namespace %q of %s %s", s.Namespace, s.Type, s.Name)
+	}
+
+	return nil
+}
+
+// isFullName reports whether name is a valid avro name, optionally qualified by a namespace.
+func isFullName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if !avroNameRegexp.MatchString(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// namespaceOr returns namespace, or the inherited one if it is empty.
+func namespaceOr(namespace, inherited string) string {
+	if namespace != "" {
+		return namespace
+	}
+
+	return inherited
+}
+
+// reference returns a reference to the named type s if it has already been defined during this inference,
+// as avro forbids redefining it. Otherwise s is recorded as defined by t, the struct of a record or nil.
+// A record is only referenced by the struct which defined it and the other named types by an identical
+// definition, another type of the same name is an error.
+func (in *inferrer) reference(s TypedSchema, t reflect.Type) (TypedSchema, bool, error) {
+	fullName := AddNamespace(s.Namespace, s.Name)
+
+	defined, ok := in.named[fullName]
+	if !ok {
+		in.named[fullName] = definition{t: t, schema: s}
+		return s, false, nil
+	}
+
+	switch {
+	case t != nil && defined.t == t, t == nil && defined.t == nil && reflect.DeepEqual(defined.schema, s):
+		return TypedSchema{Type: fullName}, true, nil
+	case defined.t != nil:
+		return s, false, fmt.Errorf("name %s already defined by type %s", fullName, typeString(defined.t))
+	case t != nil:
+		return s, false, fmt.Errorf("name %s already defined by another type", fullName)
+	}
+
+	return s, false, fmt.Errorf("name %s already defined by a different %s", fullName, defined.schema.Type)
+}
+
+// typeString returns the name of the type t qualified by its package path, which tells apart the types of the same name.
+func typeString(t reflect.Type) string {
+	if t.Name() == "" {
+		return t.String()
+	}
+
+	return t.PkgPath() + "." + t.Name()
+}
+
+// schema returns s as it is embedded in another schema: primitive types, references and unions
+// are written as is, other types as a JSON object.
+func (s TypedSchema) schema() interface{} {
+	if reflect.DeepEqual(s, TypedSchema{Type: s.Type}) {
+		return s.Type
+	}
+
+	return s
+}
+
+// typeNames returns the type of a single type expression, or the union of several ones.
+func typeNames(names []string) (interface{}, error) {
+	union := make([]interface{}, len(names))
+	for i, name := range names {
+		typ, err := typeExpr(name)
+		if err != nil {
+			return nil, err
+		}
+
+		union[i] = typ
+	}
+
+	if len(union) == 1 {
+		return union[0], nil
+	}
+
+	if err := checkUnion(union); err != nil {
+		return nil, err
+	}
+
+	return union, nil
+}
+
+// checkUnion checks that the union follows the avro rules: it can't immediately contain another union,
+// nor two branches of the same type, except for named types of different names.
+func checkUnion(union []interface{}) error {
+	branches := make(map[string]bool, len(union))
+
+	for _, branch := range union {
+		key := unionBranch(branch)
+		if key == "" {
+			return errors.New("union can't immediately contain another union")
+		}
+
+		if branches[key] {
+			return fmt.Errorf("union contains %s twice", key)
+		}
+
+		branches[key] = true
+	}
+
+	return nil
+}
+
+// checkTypes checks that the type names of typ, parsed from type expressions, are primitive types or the names of
+// the named types already defined, relative to the namespace. The full names are accepted as is, as they may name
+// the types defined by another schema.
+func (in *inferrer) checkTypes(typ interface{}, namespace string) error {
+	switch typ := typ.(type) {
+	case []interface{}:
+		for _, branch := range typ {
+			if err := in.checkTypes(branch, namespace); err != nil {
+				return err
+			}
+		}
+
+	case TypedSchema:
+		if typ.Type == "array" {
+			return in.checkTypes(typ.Items, namespace)
+		}
+
+		return in.checkTypes(typ.Values, namespace)
+
+	case string:
+		external := strings.Contains(typ, ".") && isFullName(typ)
+		_, defined := in.named[typ]
+		_, relative := in.named[AddNamespace(namespace, typ)]
+		if !isPrimitive(typ) && !external && !defined && !relative {
+			return fmt.Errorf("unknown type %q, must be a primitive type or the name of a named type", typ)
+		}
+	}
+
+	return nil
+}
+
+// typeExpr parses the type expression of a tag option: a type name, or array<T> and map<T>
+// where T is itself a type expression or a union of them, e.g. map<array<null|string>>.
+func typeExpr(expr string) (interface{}, error) {
+	for _, typ := range []string{"array", "map"} {
+		if !strings.HasPrefix(expr, typ+"<") {
+			continue
+		}
+
+		if !strings.HasSuffix(expr, ">") {
+			return nil, fmt.Errorf("unbalanced type expression %q", expr)
+		}
+
+		inner, err := typeNames(splitUnion(expr[len(typ)+1 : len(expr)-1]))
+		if err != nil {
+			return nil, err
+		}
+
+		if typ == "array" {
+			return TypedSchema{Type: typ, Items: inner}, nil
+		}
+
+		return TypedSchema{Type: typ, Values: inner}, nil
+	}
+
+	if expr == "" || strings.ContainsAny(expr, "<>") {
+		return nil, fmt.Errorf("invalid type expression %q", expr)
+	}
+
+	return expr, nil
+}
+
+// splitUnion splits the union of type expressions expr on the | which aren't nested in array<> or map<>.
+func splitUnion(expr string) []string {
+	var (
+		names []string
+		depth int
+		start int
+	)
+
+	for i, r := range expr {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case '|':
+			if depth == 0 {
+				names = append(names, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(names, expr[start:])
+}
+
+// InferSchema will infer the avro schema from a Go struct.
+// The fallbackTag parameter is the name of the struct tag to use if the avro tag is not present.
+// The v parameter is the struct to infer the schema from.
+//
+// The output is stable: the fields follow the declaration order, embedded fields being promoted in place,
+// the named types are defined where they are first used and the unions keep the order of their declaration,
+// so that a type always gives the same bytes.
+func InferSchema(fallbackTag string, v interface{}) (string, error) {
+	if v == nil {
+		return "", errNilValue
+	}
+
+	return InferSchemaType(fallbackTag, reflect.TypeOf(v))
+}
+
+// InferSchemaType will infer the avro schema of the type t, for the callers which already have a reflect.Type.
+// The fallbackTag parameter is the name of the struct tag to use if the avro tag is not present.
+func InferSchemaType(fallbackTag string, t reflect.Type, opts ...Option) (string, error) {
+	if t == nil {
+		return "", errNilType
+	}
+
+	o := newInferOptions(append([]Option{WithFallbackTag(fallbackTag)}, opts...))
+
+	return inferSchemaFromType(context.Background(), t, o)
+}
+
+// InferSchemaWithOptions will infer the avro schema from a Go struct using the given options.
+func InferSchemaWithOptions(v interface{}, opts ...Option) (string, error) {
+	if v == nil {
+		return "", errNilValue
+	}
+
+	return inferSchemaFromType(context.Background(), reflect.TypeOf(v), newInferOptions(opts))
+}
+
+// InferSchemaContext will infer the avro schema from a Go struct using the given options, like
+// InferSchemaWithOptions, aborting with the error of the context once it is done.
+func InferSchemaContext(ctx context.Context, v interface{}, opts ...Option) (string, error) {
+	if v == nil {
+		return "", errNilValue
+	}
+
+	return inferSchemaFromType(ctx, reflect.TypeOf(v), newInferOptions(opts))
+}
+
+// InferSchemaFor will infer the avro schema of the type T, without needing a value of it.
+// The fallbackTag parameter is the name of the struct tag to use if the avro tag is not present.
+func InferSchemaFor[T any](fallbackTag string, opts ...Option) (string, error) {
+	return InferSchemaType(fallbackTag, reflect.TypeOf((*T)(nil)).Elem(), opts...)
+}
+
+// InferSchemaTree will infer the avro schema from a Go struct like InferSchema,
+// but returns it as a TypedSchema so it can be modified before being marshaled to JSON.
+func InferSchemaTree(fallbackTag string, v interface{}, opts ...Option) (TypedSchema, error) {
+	if v == nil {
+		return TypedSchema{}, errNilValue
+	}
+
+	o := newInferOptions(append([]Option{WithFallbackTag(fallbackTag)}, opts...))
+
+	return inferTree(context.Background(), reflect.TypeOf(v), o)
+}
+
+var (
+	errNilValue = errors.New("cannot infer schema from nil value")
+	errNilType  = errors.New("cannot infer schema from nil type")
+)
+
+// pathError is an inference error along with the path of the Go field it occurred on, e.g. A.E.Items[].Value.
+type pathError struct {
+	path string
+	err  error
+}
+
+func (e *pathError) Error() string {
+	return e.path + ": " + e.err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+	return e.err
+}
+
+// inferErrors are the errors of several fields, collected with WithCollectErrors.
+type inferErrors []error
+
+func (errs inferErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (errs inferErrors) Unwrap() []error {
+	return errs
+}
+
+// append appends err, or the errors it holds, to errs.
+func (errs inferErrors) append(err error) inferErrors {
+	if nested, ok := err.(inferErrors); ok {
+		return append(errs, nested...)
+	}
+
+	return append(errs, err)
+}
+
+// prependPath prepends the field name, or [] for the elements of slices and maps, to the path of err,
+// or of each of the errors it holds.
+func prependPath(segment string, err error) error {
+	if errs, ok := err.(inferErrors); ok {
+		for i := range errs {
+			errs[i] = prependPath(segment, errs[i])
+		}
+
+		return errs
+	}
+
+	pe, ok := err.(*pathError)
+	if !ok {
+		return &pathError{path: segment, err: err}
+	}
+
+	if strings.HasPrefix(pe.path, "[") {
+		pe.path = segment + pe.path
+	} else {
+		pe.path = segment + "." + pe.path
+	}
+
+	return err
+}
+
+func inferTree(ctx context.Context, t reflect.Type, opts inferOptions) (TypedSchema, error) {
+	in := inferrer{
+		ctx:          ctx,
+		inferOptions: opts,
+		named:        make(map[string]definition),
+		visiting:     make(map[reflect.Type]bool),
+	}
+
+	// a top-level pointer is the value it points to, the schema of a message not being nullable
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	s, err := in.inferSchema(t, tagOptions{namespace: opts.namespace, name: opts.recordName})
+	if err != nil {
+		// the errors of the root type itself have no path
+		switch err.(type) {
+		case *pathError, inferErrors:
+			if t.Name() != "" {
+				err = prependPath(t.Name(), err)
+			}
+		}
+
+		return s, fmt.Errorf("infer schema: %w", err)
+	}
+
+	if s.Type == "record" {
+		for _, alias := range opts.aliases {
+			if !isFullName(alias) {
+				return s, fmt.Errorf("invalid alias %q", alias)
+			}
+		}
+
+		if opts.doc != "" {
+			s.Doc = opts.doc
+		}
+
+		if opts.aliases != nil {
+			s.Aliases = opts.aliases
+		}
+	}
+
+	return s, nil
+}
+
+func inferSchemaFromType(ctx context.Context, t reflect.Type, opts inferOptions) (string, error) {
+	// a cached schema is only returned while the context is still running
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("infer schema: %w", err)
+		}
+	}
+
+	key := newSchemaCacheKey(t, opts)
+	if !opts.noCache {
+		if schema, ok := schemaCache.Load(key); ok {
+			return schema.(string), nil
+		}
+	}
+
+	s, err := inferTree(ctx, t, opts)
+	if err != nil {
+		return "", err
+	}
+
+	schema, err := marshalSchema(s, opts.indent)
+	if err != nil {
+		return "", err
+	}
+
+	if !opts.noCache {
+		schemaCache.Store(key, schema)
+	}
+
+	return schema, nil
+}
+
+// marshalSchema returns the JSON of the schema s, pretty-printed if indent is not empty.
+func marshalSchema(s TypedSchema, indent string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	if indent != "" {
+		b, err = json.MarshalIndent(s, "", indent)
+	} else {
+		b, err = json.Marshal(s)
 	}
 
-	b, err := json.Marshal(s)
 	if err != nil {
 		return "", fmt.Errorf("marshal schema: %w", err)
 	}
@@ -0,0 +1,234 @@
+package avro
+
+// inferOptions configures the schema inference.
+type inferOptions struct {
+	// tagKey is the key of the struct tag holding the avro name and options, avro when empty.
+	tagKey string
+	// fallbackTags are the names of the struct tags to use if the avro tag is not present, in priority order.
+	fallbackTags []string
+	// recordName names the top-level record instead of its Go type name.
+	recordName string
+	// namespace is the namespace of the top-level record, inherited by the nested named types.
+	namespace string
+	// doc is the documentation of the top-level record.
+	doc string
+	// aliases are the alternate names of the top-level record.
+	aliases []string
+	// indent pretty-prints the schema when not empty.
+	indent string
+	// nullLast puts null as the last branch of the unions inferred from pointers.
+	nullLast bool
+	// omitEmptyNullable makes the fields whose fallback tag has omitempty nullable.
+	omitEmptyNullable bool
+	// nameMapper maps the names of the untagged fields.
+	nameMapper func(string) string
+	// noCache infers the schema again instead of returning the cached one.
+	noCache bool
+	// stringifyMapKeys allows the maps whose keys are integers or fmt.Stringer.
+	stringifyMapKeys bool
+	// emptyArrayItems is the item type of the empty arrays of the JSON samples.
+	emptyArrayItems string
+	// piiProp and deprecatedProp are the custom attributes set by the pii and deprecated tag options,
+	// x-pii and x-deprecated when empty.
+	piiProp, deprecatedProp string
+	// collectErrors returns the errors of all the fields instead of the first one.
+	collectErrors bool
+	// strictErrors infers the fields of type error as the other interfaces, instead of nullable strings.
+	strictErrors bool
+	// dropBaseFields drops the fields of the base schema of InferSchemaMerge missing from the struct.
+	dropBaseFields bool
+	// zeroDefaults sets the zero values of the booleans, numbers and strings as the defaults of their fields.
+	zeroDefaults bool
+}
+
+// Option configures the schema inference of InferSchemaWithOptions.
+type Option func(*inferOptions)
+
+func newInferOptions(opts []Option) inferOptions {
+	var o inferOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithTagKey reads the avro name and options of the fields, such as type= or items=, from the struct tag key
+// instead of avro. The fallback tags are consulted the same when it is absent.
+func WithTagKey(key string) Option {
+	return func(o *inferOptions) {
+		o.tagKey = key
+	}
+}
+
+// WithFallbackTag sets the name of the struct tag to use if the avro tag is not present.
+func WithFallbackTag(tag string) Option {
+	return func(o *inferOptions) {
+		o.fallbackTags = []string{tag}
+	}
+}
+
+// WithFallbackTags sets the names of the struct tags to use if the avro tag is not present, in priority order:
+// the field is named by the first of them which is present and not empty.
+func WithFallbackTags(tags ...string) Option {
+	return func(o *inferOptions) {
+		o.fallbackTags = tags
+	}
+}
+
+// WithRecordName sets the name of the top-level record, which is the name of its Go type by default.
+// It is required to infer the schema of an anonymous struct.
+func WithRecordName(name string) Option {
+	return func(o *inferOptions) {
+		o.recordName = name
+	}
+}
+
+// WithNamespace sets the namespace of the top-level record, inherited by the nested named types.
+func WithNamespace(namespace string) Option {
+	return func(o *inferOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithDoc sets the documentation of the top-level record.
+func WithDoc(doc string) Option {
+	return func(o *inferOptions) {
+		o.doc = doc
+	}
+}
+
+// WithAliases sets the alternate names of the top-level record.
+func WithAliases(aliases ...string) Option {
+	return func(o *inferOptions) {
+		o.aliases = aliases
+	}
+}
+
+// WithIndent pretty-prints the schema, indenting it with the given string.
+// The schema is minified by default.
+func WithIndent(indent string) Option {
+	return func(o *inferOptions) {
+		o.indent = indent
+	}
+}
+
+// NullOrder is the position of null in the unions inferred from pointers.
+type NullOrder int
+
+const (
+	// NullFirst puts null as the first branch and defaults the fields to null, following the avro convention.
+	NullFirst NullOrder = iota
+	// NullLast puts null as the last branch, without a default.
+	NullLast
+)
+
+// WithNullUnionOrder sets the position of null in the unions inferred from pointers, NullFirst by default.
+// WithNullFirst and WithNullLast are shorthands for it.
+func WithNullUnionOrder(order NullOrder) Option {
+	return func(o *inferOptions) {
+		o.nullLast = order == NullLast
+	}
+}
+
+// WithNullFirst puts null as the first branch of the unions inferred from pointers and defaults
+// their fields to null, following the avro convention. This is the default.
+func WithNullFirst() Option {
+	return func(o *inferOptions) {
+		o.nullLast = false
+	}
+}
+
+// WithNullLast puts null as the last branch of the unions inferred from pointers, without a default.
+func WithNullLast() Option {
+	return func(o *inferOptions) {
+		o.nullLast = true
+	}
+}
+
+// WithOmitEmptyNullable makes the fields without an avro tag but whose fallback tag has the omitempty option,
+// as in json:"x,omitempty", nullable unions defaulting to null.
+func WithOmitEmptyNullable() Option {
+	return func(o *inferOptions) {
+		o.omitEmptyNullable = true
+	}
+}
+
+// WithNameMapper maps the Go names of the fields which aren't named by a tag, e.g. to snake case them.
+// The tagged names are left untouched.
+// The inferred schemas aren't cached, as two mappers can't be told apart.
+func WithNameMapper(mapper func(string) string) Option {
+	return func(o *inferOptions) {
+		o.nameMapper = mapper
+		o.noCache = true
+	}
+}
+
+// WithoutCache infers the schema again, even if the same type has already been inferred with the same options,
+// and doesn't cache the result.
+func WithoutCache() Option {
+	return func(o *inferOptions) {
+		o.noCache = true
+	}
+}
+
+// WithStringifyMapKeys allows the Go maps whose keys are integers or implement fmt.Stringer, avro maps having
+// string keys. Marshal writes the integer keys in base 10 and the other keys with their String method,
+// Unmarshal parses the integer keys back and the keys implementing encoding.TextUnmarshaler.
+func WithStringifyMapKeys() Option {
+	return func(o *inferOptions) {
+		o.stringifyMapKeys = true
+	}
+}
+
+// WithEmptyArrayItems sets the item type of the arrays which are empty in the samples given to InferSchemaFromJSON,
+// as a type expression of the items= tag option such as string or null|long.
+// Such arrays are an error by default, as their item type can't be inferred.
+func WithEmptyArrayItems(typ string) Option {
+	return func(o *inferOptions) {
+		o.emptyArrayItems = typ
+	}
+}
+
+// WithFlagProps sets the names of the custom attributes set to true by the pii and deprecated tag options,
+// as in avro:"ssn,pii,deprecated", which are x-pii and x-deprecated by default or when empty.
+func WithFlagProps(pii, deprecated string) Option {
+	return func(o *inferOptions) {
+		o.piiProp = pii
+		o.deprecatedProp = deprecated
+	}
+}
+
+// WithStrictErrors requires an explicit union, such as type=null|string, for the fields of type error as for
+// the other interfaces. They are nullable strings holding the error message by default, which can be overridden
+// by registering the error type with RegisterType.
+func WithStrictErrors() Option {
+	return func(o *inferOptions) {
+		o.strictErrors = true
+	}
+}
+
+// WithCollectErrors infers all the fields of the structs and returns the errors of all of them, one per line
+// and each along with the path of its field, instead of stopping at the first one.
+func WithCollectErrors() Option {
+	return func(o *inferOptions) {
+		o.collectErrors = true
+	}
+}
+
+// WithDropBaseFields drops the fields of the base schema of InferSchemaMerge which are missing from the struct,
+// instead of failing.
+func WithDropBaseFields() Option {
+	return func(o *inferOptions) {
+		o.dropBaseFields = true
+	}
+}
+
+// WithZeroDefaults sets the zero value of the fields of type boolean, int, long, float, double and string as their
+// default, as in "default":0, unless they have a default= tag option or a logical type. The other types, such as
+// the enums, records, arrays and maps, don't have a default which reads as their Go zero value.
+func WithZeroDefaults() Option {
+	return func(o *inferOptions) {
+		o.zeroDefaults = true
+	}
+}
@@ -1,12 +1,31 @@
 package avro
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// platformInt is the avro type of a Go int on the current platform
+var platformInt = map[int]string{32: "int", 64: "long"}[strconv.IntSize]
+
 type E struct {
 	F string
 }
@@ -17,6 +36,108 @@ type A struct {
 	E E
 }
 
+type Event struct {
+	At      time.Time  `avro:"at"`
+	Micros  time.Time  `avro:"micros,logicalType=timestamp-micros"`
+	Expires *time.Time `avro:"expires"`
+}
+
+type BadTimestamp struct {
+	At time.Time `avro:"at,logicalType=date-time"`
+}
+
+type Payment struct {
+	Amount   big.Rat  `avro:"amount,logicalType=decimal,precision=10,scale=2"`
+	Total    big.Int  `avro:"total,precision=38,size=16"`
+	Discount *big.Rat `avro:"discount,precision=4,scale=4"`
+}
+
+type BadDecimal struct {
+	Amount big.Rat `avro:"amount,scale=2"`
+}
+
+type Blobs struct {
+	Data   []byte     `avro:"data"`
+	Hash   [16]byte   `avro:"hash"`
+	Chunks [][]byte   `avro:"chunks"`
+	Keys   [][32]byte `avro:"keys"`
+}
+
+type Digest [20]byte
+
+type Checksums struct {
+	SHA1   Digest   `avro:"sha1"`
+	UUID   [16]byte `avro:"uuid,name=uuid"`
+	Points [3]int32 `avro:"points"`
+}
+
+type Measure struct {
+	Single float32  `avro:"single"`
+	Double float64  `avro:"double"`
+	Maybe  *float32 `avro:"maybe"`
+}
+
+type Blob []byte
+
+type Octet uint8
+
+type IDs []string
+
+type Payloads struct {
+	Raw    json.RawMessage `avro:"raw"`
+	Blob   Blob            `avro:"blob"`
+	Octets []Octet         `avro:"octets"`
+	IDs    IDs             `avro:"ids"`
+}
+
+type Color string
+
+type Palette struct {
+	Color  Color  `avro:"color,enum=RED|GREEN|BLUE"`
+	Shade  string `avro:"shade,enum=LIGHT|DARK,default=DARK"`
+	Accent *Color `avro:"accent,enum=RED|GREEN|BLUE"`
+}
+
+type BadEnumSymbol struct {
+	Color Color `avro:"color,enum=RED|light-green"`
+}
+
+type BadEnumDefault struct {
+	Color Color `avro:"color,enum=RED|GREEN,default=BLUE"`
+}
+
+type WithSkipped struct {
+	ID       string `avro:"id"`
+	Internal string `avro:"-"`
+	Name     string `json:"name"`
+	Cache    []byte `json:"-"`
+	Secret   func() `avro:"-"`
+	Age      int32
+}
+
+type WithUnexported struct {
+	ID      string `avro:"id"`
+	secret  string
+	counter int `avro:"counter"`
+}
+
+type Optional struct {
+	Name  *string  `avro:"name"`
+	Items []*int32 `avro:"items"`
+}
+
+type Location struct {
+	Street string `avro:"street"`
+}
+
+type Customer struct {
+	Home    Location   `avro:"home"`
+	Billing *Location  `avro:"billing,namespace=com.acme.billing"`
+	Tier    string     `avro:"tier,enum=GOLD|SILVER"`
+	Key     [8]byte    `avro:"key"`
+	History []Location `avro:"history"`
+}
+
 func TestInferSchema(t *testing.T) {
 	type args struct {
 		v interface{}
@@ -38,9 +159,71 @@ func TestInferSchema(t *testing.T) {
 					},
 				},
 			},
-			want:    `{"name":"A","type":"record","fields":[{"name":"b","type":"string"},{"name":"C","type":"int"},{"name":"E","type":"record","fields":[{"name":"F","type":"string"}]}]}`,
+			want:    `{"name":"A","type":"record","fields":[{"name":"b","type":"string"},{"name":"C","type":"` + platformInt + `"},{"name":"E","type":{"name":"E","type":"record","fields":[{"name":"F","type":"string"}]}}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "time as timestamp logical type",
+			args:    args{v: Event{}},
+			want:    `{"name":"Event","type":"record","fields":[{"name":"at","type":{"type":"long","logicalType":"timestamp-millis"}},{"name":"micros","type":{"type":"long","logicalType":"timestamp-micros"}},{"name":"expires","type":["null",{"type":"long","logicalType":"timestamp-millis"}],"default":null}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "big numbers as decimal logical type",
+			args:    args{v: Payment{}},
+			want:    `{"name":"Payment","type":"record","fields":[{"name":"amount","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},{"name":"total","type":{"name":"Payment_total_decimal","type":"fixed","size":16,"logicalType":"decimal","precision":38}},{"name":"discount","type":["null",{"type":"bytes","logicalType":"decimal","precision":4,"scale":4}],"default":null}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "byte slices and arrays as bytes and fixed",
+			args:    args{v: Blobs{}},
+			want:    `{"name":"Blobs","type":"record","fields":[{"name":"data","type":"bytes"},{"name":"hash","type":{"name":"Blobs_hash_fixed","type":"fixed","size":16}},{"name":"chunks","type":{"type":"array","items":"bytes"}},{"name":"keys","type":{"type":"array","items":{"name":"Blobs_keys_items_fixed","type":"fixed","size":32}}}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "fixed names and non-byte arrays",
+			args:    args{v: Checksums{}},
+			want:    `{"name":"Checksums","type":"record","fields":[{"name":"sha1","type":{"name":"Digest","type":"fixed","size":20}},{"name":"uuid","type":{"name":"uuid","type":"fixed","size":16}},{"name":"points","type":{"type":"array","items":"int"}}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "float32 as float and float64 as double",
+			args:    args{v: Measure{}},
+			want:    `{"name":"Measure","type":"record","fields":[{"name":"single","type":"float"},{"name":"double","type":"double"},{"name":"maybe","type":["null","float"],"default":null}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "named byte slices as bytes",
+			args:    args{v: Payloads{}},
+			want:    `{"name":"Payloads","type":"record","fields":[{"name":"raw","type":"bytes"},{"name":"blob","type":"bytes"},{"name":"octets","type":"bytes"},{"name":"ids","type":{"type":"array","items":"string"}}]}`,
 			wantErr: assert.NoError,
 		},
+		{
+			name:    "enum from tag",
+			args:    args{v: Palette{}},
+			want:    `{"name":"Palette","type":"record","fields":[{"name":"color","type":{"name":"Color","type":"enum","symbols":["RED","GREEN","BLUE"]}},{"name":"shade","type":{"name":"shade","type":"enum","symbols":["LIGHT","DARK"]},"default":"DARK"},{"name":"accent","type":["null","Color"],"default":null}]}`,
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "enum with invalid symbol",
+			args:    args{v: BadEnumSymbol{}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "enum with unknown default",
+			args:    args{v: BadEnumDefault{}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "decimal without precision",
+			args:    args{v: BadDecimal{}},
+			wantErr: assert.Error,
+		},
+		{
+			name:    "unsupported time logical type",
+			args:    args{v: BadTimestamp{}},
+			wantErr: assert.Error,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -55,3 +238,1932 @@ func TestInferSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestInferSchema_skipped_fields(t *testing.T) {
+	got, err := InferSchema("json", WithSkipped{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"WithSkipped","type":"record","fields":[{"name":"id","type":"string"},{"name":"name","type":"string"},{"name":"Age","type":"int"}]}`, got)
+}
+
+func TestInferSchema_unexported_fields(t *testing.T) {
+	got, err := InferSchema("avro", WithUnexported{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"WithUnexported","type":"record","fields":[{"name":"id","type":"string"}]}`, got)
+}
+
+func TestInferSchemaWithOptions_null_union_order(t *testing.T) {
+	got, err := InferSchemaWithOptions(Optional{}, WithFallbackTag("avro"), WithNullFirst())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Optional","type":"record","fields":[{"name":"name","type":["null","string"],"default":null},{"name":"items","type":{"type":"array","items":["null","int"]}}]}`, got)
+
+	got, err = InferSchemaWithOptions(Optional{}, WithFallbackTag("avro"), WithNullLast())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Optional","type":"record","fields":[{"name":"name","type":["string","null"]},{"name":"items","type":{"type":"array","items":["int","null"]}}]}`, got)
+
+	for order, want := range map[NullOrder]string{
+		NullFirst: `{"name":"Shipment","type":"record","fields":[` +
+			`{"name":"from","type":{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}},` +
+			`{"name":"to","type":["null","Location"],"default":null},` +
+			`{"name":"stops","type":{"type":"map","values":"Location"}}]}`,
+		NullLast: `{"name":"Shipment","type":"record","fields":[` +
+			`{"name":"from","type":{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}},` +
+			`{"name":"to","type":["Location","null"]},` +
+			`{"name":"stops","type":{"type":"map","values":"Location"}}]}`,
+	} {
+		got, err = InferSchemaWithOptions(Shipment{}, WithFallbackTag("avro"), WithNullUnionOrder(order))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	// the last option wins
+	got, err = InferSchemaWithOptions(Optional{}, WithFallbackTag("avro"), WithNullUnionOrder(NullLast), WithNullFirst())
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"name","type":["null","string"],"default":null}`)
+}
+
+func Test_inferType_integers(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{int8(0), "int"},
+		{int16(0), "int"},
+		{int32(0), "int"},
+		{int64(0), "long"},
+		{int(0), platformInt},
+		{uint8(0), "int"},
+		{uint16(0), "int"},
+		{uint32(0), "long"},
+		{uint64(0), "long"},
+		{uint(0), "long"},
+	}
+	for _, tt := range tests {
+		typ := reflect.TypeOf(tt.v)
+		t.Run(typ.Kind().String(), func(t *testing.T) {
+			got, err := inferType(typ)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInferSchemaFor(t *testing.T) {
+	got, err := InferSchemaFor[A]("avro")
+	assert.NoError(t, err)
+
+	want, err := InferSchema("avro", A{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = InferSchemaFor[fmt.Stringer]("avro")
+	assert.Error(t, err)
+}
+
+func TestInferSchemaType(t *testing.T) {
+	got, err := InferSchemaType("avro", reflect.TypeOf(A{}))
+	assert.NoError(t, err)
+
+	want, err := InferSchema("avro", A{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	got, err = InferSchemaType("avro", reflect.TypeOf(&A{}), WithNamespace("com.example"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"namespace":"com.example"`)
+
+	_, err = InferSchemaType("avro", nil)
+	assert.EqualError(t, err, "cannot infer schema from nil type")
+}
+
+func TestInferSchema_nil(t *testing.T) {
+	_, err := InferSchema("avro", nil)
+	assert.EqualError(t, err, "cannot infer schema from nil value")
+
+	record, err := InferSchema("avro", A{})
+	assert.NoError(t, err)
+
+	got, err := InferSchema("avro", (*A)(nil))
+	assert.NoError(t, err)
+	assert.Contains(t, got, record)
+}
+
+func TestInferSchemaWithOptions_namespace(t *testing.T) {
+	got, err := InferSchemaWithOptions(Customer{}, WithFallbackTag("avro"), WithNamespace("com.acme"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Customer","namespace":"com.acme","type":"record","fields":[`+
+		`{"name":"home","type":{"name":"Location","namespace":"com.acme","type":"record","fields":[{"name":"street","type":"string"}]}},`+
+		`{"name":"billing","type":["null",{"name":"Location","namespace":"com.acme.billing","type":"record","fields":[{"name":"street","type":"string"}]}],"default":null},`+
+		`{"name":"tier","type":{"name":"tier","namespace":"com.acme","type":"enum","symbols":["GOLD","SILVER"]}},`+
+		`{"name":"key","type":{"name":"Customer_key_fixed","namespace":"com.acme","type":"fixed","size":8}},`+
+		`{"name":"history","type":{"type":"array","items":"com.acme.Location"}}]}`, got)
+}
+
+type Shipment struct {
+	From  Location            `avro:"from"`
+	To    *Location           `avro:"to"`
+	Stops map[string]Location `avro:"stops"`
+}
+
+func TestInferSchema_named_type_references(t *testing.T) {
+	got, err := InferSchema("avro", Shipment{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Shipment","type":"record","fields":[`+
+		`{"name":"from","type":{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}},`+
+		`{"name":"to","type":["null","Location"],"default":null},`+
+		`{"name":"stops","type":{"type":"map","values":"Location"}}]}`, got)
+}
+
+type Node struct {
+	Value    int32  `avro:"value"`
+	Next     *Node  `avro:"next"`
+	Children []Node `avro:"children"`
+}
+
+type Tree map[string]Tree
+
+func TestInferSchema_recursive_types(t *testing.T) {
+	got, err := InferSchema("avro", Node{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Node","type":"record","fields":[{"name":"value","type":"int"},{"name":"next","type":["null","Node"],"default":null},{"name":"children","type":{"type":"array","items":"Node"}}]}`, got)
+
+	_, err = InferSchema("avro", Tree{})
+	assert.Error(t, err)
+}
+
+type Audit struct {
+	CreatedBy string `avro:"created_by"`
+	Version   int32  `avro:"version"`
+}
+
+type revision struct {
+	Revision int32 `avro:"revision"`
+}
+
+type Document struct {
+	Audit
+	*revision
+	Title   string `avro:"title"`
+	Version string `avro:"version"`
+}
+
+type TaggedDocument struct {
+	Audit `avro:"audit"`
+	Title string `avro:"title"`
+}
+
+func TestInferSchema_embedded_structs(t *testing.T) {
+	got, err := InferSchema("avro", Document{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Document","type":"record","fields":[{"name":"created_by","type":"string"},{"name":"revision","type":"int"},{"name":"title","type":"string"},{"name":"version","type":"string"}]}`, got)
+
+	got, err = InferSchema("avro", TaggedDocument{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"TaggedDocument","type":"record","fields":[{"name":"audit","type":{"name":"Audit","type":"record","fields":[{"name":"created_by","type":"string"},{"name":"version","type":"int"}]}},{"name":"title","type":"string"}]}`, got)
+}
+
+type Owned struct {
+	ID    string
+	Owner string `avro:"owner"`
+}
+
+type Labeled struct {
+	ID    int64
+	Label string `avro:"label"`
+}
+
+type Keyed struct {
+	Key string `avro:"ID"`
+}
+
+func TestInferSchema_ambiguous_promoted_fields(t *testing.T) {
+	// the untagged fields of the same name at the same depth are dropped, as with encoding/json
+	type Item struct {
+		Owned
+		Labeled
+		Name string `avro:"name"`
+	}
+
+	got, err := InferSchema("avro", Item{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Item","type":"record","fields":[`+
+		`{"name":"owner","type":"string"},{"name":"label","type":"string"},{"name":"name","type":"string"}]}`, got)
+
+	data, err := Marshal(got, Item{Owned{ID: "a", Owner: "bob"}, Labeled{ID: 1, Label: "x"}, "item"})
+	assert.NoError(t, err)
+
+	var decoded Item
+	assert.NoError(t, Unmarshal(got, data, &decoded))
+	assert.Equal(t, Item{Owned{Owner: "bob"}, Labeled{Label: "x"}, "item"}, decoded)
+
+	// unless a single one is tagged
+	type KeyedItem struct {
+		Owned
+		Keyed
+	}
+
+	got, err = InferSchema("avro", KeyedItem{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"KeyedItem","type":"record","fields":[{"name":"owner","type":"string"},{"name":"ID","type":"string"}]}`, got)
+
+	data, err = Marshal(got, KeyedItem{Owned{ID: "a", Owner: "bob"}, Keyed{Key: "k"}})
+	assert.NoError(t, err)
+
+	var keyed KeyedItem
+	assert.NoError(t, Unmarshal(got, data, &keyed))
+	assert.Equal(t, KeyedItem{Owned{Owner: "bob"}, Keyed{Key: "k"}}, keyed)
+}
+
+type Documented struct {
+	ID    string `avro:"id,doc=Unique identifier"`
+	Notes string `avro:"notes" avrodoc:"Free text, written by the customer"`
+}
+
+func TestInferSchemaWithOptions_doc(t *testing.T) {
+	got, err := InferSchemaWithOptions(Documented{}, WithFallbackTag("avro"), WithDoc("A documented record"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Documented","doc":"A documented record","type":"record","fields":[{"name":"id","doc":"Unique identifier","type":"string"},{"name":"notes","doc":"Free text, written by the customer","type":"string"}]}`, got)
+}
+
+type Defaults struct {
+	Count    int32    `avro:"count,default=42"`
+	Ratio    float64  `avro:"ratio,default=0.5"`
+	Enabled  bool     `avro:"enabled,default=true"`
+	Label    string   `avro:"label,default=none"`
+	Empty    string   `avro:"empty,default="`
+	Tags     []string `avro:"tags,default=[]"`
+	Nickname *string  `avro:"nickname,default=null"`
+	Level    string   `avro:"level,enum=LOW|HIGH,default=LOW"`
+}
+
+type BadIntDefault struct {
+	Count int32 `avro:"count,default=many"`
+}
+
+type BadUnionDefault struct {
+	Nickname *string `avro:"nickname,default=bob"`
+}
+
+func TestInferSchema_defaults(t *testing.T) {
+	got, err := InferSchema("avro", Defaults{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Defaults","type":"record","fields":[`+
+		`{"name":"count","type":"int","default":42},`+
+		`{"name":"ratio","type":"double","default":0.5},`+
+		`{"name":"enabled","type":"boolean","default":true},`+
+		`{"name":"label","type":"string","default":"none"},`+
+		`{"name":"empty","type":"string","default":""},`+
+		`{"name":"tags","type":{"type":"array","items":"string"},"default":[]},`+
+		`{"name":"nickname","type":["null","string"],"default":null},`+
+		`{"name":"level","type":{"name":"level","type":"enum","symbols":["LOW","HIGH"]},"default":"LOW"}]}`, got)
+
+	_, err = InferSchema("avro", BadIntDefault{})
+	assert.Error(t, err)
+
+	// the default of a union must match its first branch, null for pointers
+	_, err = InferSchema("avro", BadUnionDefault{})
+	assert.Error(t, err)
+
+	got, err = InferSchemaWithOptions(BadUnionDefault{}, WithFallbackTag("avro"), WithNullLast())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"BadUnionDefault","type":"record","fields":[{"name":"nickname","type":["string","null"],"default":"bob"}]}`, got)
+}
+
+type Renamed struct {
+	FullName string `avro:"full_name,aliases=name|display_name"`
+}
+
+type BadAlias struct {
+	FullName string `avro:"full_name,aliases=full-name"`
+}
+
+func TestInferSchemaWithOptions_aliases(t *testing.T) {
+	got, err := InferSchemaWithOptions(Renamed{}, WithFallbackTag("avro"), WithAliases("com.acme.User"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Renamed","aliases":["com.acme.User"],"type":"record","fields":[{"name":"full_name","aliases":["name","display_name"],"type":"string"}]}`, got)
+
+	_, err = InferSchema("avro", BadAlias{})
+	assert.Error(t, err)
+
+	_, err = InferSchemaWithOptions(Renamed{}, WithAliases("com.acme-corp.User"))
+	assert.Error(t, err)
+}
+
+type Ranked struct {
+	Score int32  `avro:"score,order=descending"`
+	Name  string `avro:"name,order=ignore"`
+}
+
+type BadOrder struct {
+	Score int32 `avro:"score,order=random"`
+}
+
+func TestInferSchema_order(t *testing.T) {
+	got, err := InferSchema("avro", Ranked{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Ranked","type":"record","fields":[{"name":"score","type":"int","order":"descending"},{"name":"name","type":"string","order":"ignore"}]}`, got)
+
+	_, err = InferSchema("avro", BadOrder{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ascending, descending or ignore")
+	}
+}
+
+func TestInferSchemaWithOptions_indent(t *testing.T) {
+	got, err := InferSchemaWithOptions(E{}, WithFallbackTag("avro"), WithIndent("  "))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "name": "E",
+  "type": "record",
+  "fields": [
+    {
+      "name": "F",
+      "type": "string"
+    }
+  ]
+}`, got)
+}
+
+func TestInferSchemaTree(t *testing.T) {
+	got, err := InferSchemaTree("avro", A{})
+	assert.NoError(t, err)
+	assert.Equal(t, TypedSchema{
+		Name: "A",
+		Type: "record",
+		Fields: []TypedSchema{
+			{Name: "b", Type: "string"},
+			{Name: "C", Type: platformInt},
+			{Name: "E", Type: TypedSchema{Name: "E", Type: "record", Fields: []TypedSchema{{Name: "F", Type: "string"}}}},
+		},
+	}, got)
+
+	_, err = InferSchemaTree("avro", nil)
+	assert.Error(t, err)
+}
+
+func TestInferSchemaWithOptions_functional_options(t *testing.T) {
+	legacy, err := InferSchema("json", WithSkipped{})
+	assert.NoError(t, err)
+
+	got, err := InferSchemaWithOptions(WithSkipped{}, WithFallbackTag("json"))
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, got)
+
+	got, err = InferSchemaWithOptions(Optional{}, WithFallbackTag("avro"), WithNullLast(), WithNullFirst())
+	assert.NoError(t, err)
+	want, err := InferSchemaWithOptions(Optional{}, WithFallbackTag("avro"))
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+type Amount struct {
+	Units int64
+	Nanos int32
+}
+
+type Invoice struct {
+	Total    Amount  `avro:"total"`
+	Discount *Amount `avro:"discount"`
+	Lines    []Amount
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType(reflect.TypeOf(Amount{}), func() TypedSchema {
+		return TypedSchema{Type: "bytes", LogicalType: "decimal", Precision: 18, Scale: 9}
+	})
+	defer RegisterType(reflect.TypeOf(Amount{}), nil)
+
+	got, err := InferSchema("avro", Invoice{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Invoice","type":"record","fields":[`+
+		`{"name":"total","type":{"type":"bytes","logicalType":"decimal","precision":18,"scale":9}},`+
+		`{"name":"discount","type":["null",{"type":"bytes","logicalType":"decimal","precision":18,"scale":9}],"default":null},`+
+		`{"name":"Lines","type":{"type":"array","items":{"type":"bytes","logicalType":"decimal","precision":18,"scale":9}}}]}`, got)
+}
+
+func TestRegisterType_named(t *testing.T) {
+	RegisterType(reflect.TypeOf(Amount{}), func() TypedSchema {
+		return TypedSchema{Name: "com.acme.Money", Type: "fixed", Size: 16}
+	})
+	defer RegisterType(reflect.TypeOf(Amount{}), nil)
+
+	got, err := InferSchema("avro", Invoice{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Invoice","type":"record","fields":[`+
+		`{"name":"total","type":{"name":"com.acme.Money","type":"fixed","size":16}},`+
+		`{"name":"discount","type":["null","com.acme.Money"],"default":null},`+
+		`{"name":"Lines","type":{"type":"array","items":"com.acme.Money"}}]}`, got)
+}
+
+type Celsius int32
+
+type Level int
+
+type Readings struct {
+	Indoor  Celsius  `avro:"indoor"`
+	Outdoor *Celsius `avro:"outdoor"`
+	Raw     int32    `avro:"raw"`
+	Level   Level    `avro:"level"`
+}
+
+func TestRegisterType_named_primitive(t *testing.T) {
+	got, err := InferSchema("avro", Readings{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"indoor","type":"int"}`)
+
+	RegisterType(reflect.TypeOf(Celsius(0)), func() TypedSchema {
+		return TypedSchema{Type: "int", LogicalType: "temperature-celsius"}
+	})
+	defer RegisterType(reflect.TypeOf(Celsius(0)), nil)
+
+	RegisterType(reflect.TypeOf(Level(0)), func() TypedSchema {
+		return TypedSchema{Type: "enum", Name: "Level", Symbols: []string{"LOW", "HIGH"}}
+	})
+	defer RegisterType(reflect.TypeOf(Level(0)), nil)
+
+	got, err = InferSchema("avro", Readings{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Readings","type":"record","fields":[`+
+		`{"name":"indoor","type":{"type":"int","logicalType":"temperature-celsius"}},`+
+		`{"name":"outdoor","type":["null",{"type":"int","logicalType":"temperature-celsius"}],"default":null},`+
+		`{"name":"raw","type":"int"},`+
+		`{"name":"level","type":{"name":"Level","type":"enum","symbols":["LOW","HIGH"]}}]}`, got)
+
+	// the exact type is registered, not its kind
+	raw, err := InferSchemaFor[int32]("avro")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"int"}`, raw)
+}
+
+type Row struct {
+	Name    sql.NullString  `avro:"name"`
+	Age     sql.NullInt64   `avro:"age"`
+	Active  sql.NullBool    `avro:"active"`
+	Score   sql.NullFloat64 `avro:"score"`
+	Updated sql.NullTime    `avro:"updated"`
+}
+
+func TestInferSchema_sql_null_types(t *testing.T) {
+	got, err := InferSchema("avro", Row{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Row","type":"record","fields":[`+
+		`{"name":"name","type":["null","string"],"default":null},`+
+		`{"name":"age","type":["null","long"],"default":null},`+
+		`{"name":"active","type":["null","boolean"],"default":null},`+
+		`{"name":"score","type":["null","double"],"default":null},`+
+		`{"name":"updated","type":["null",{"type":"long","logicalType":"timestamp-millis"}],"default":null}]}`, got)
+}
+
+func TestInferSchema_cache(t *testing.T) {
+	ClearSchemaCache()
+
+	want, err := InferSchema("avro", Invoice{})
+	assert.NoError(t, err)
+
+	_, ok := schemaCache.Load(newSchemaCacheKey(reflect.TypeOf(Invoice{}), newInferOptions([]Option{WithFallbackTag("avro")})))
+	assert.True(t, ok)
+
+	got, err := InferSchema("avro", Invoice{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// the options are part of the key
+	got, err = InferSchemaWithOptions(Invoice{}, WithFallbackTag("avro"), WithNamespace("com.example"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, want, got)
+
+	// registering a type invalidates the cache
+	RegisterType(reflect.TypeOf(Amount{}), func() TypedSchema { return TypedSchema{Type: "string"} })
+	defer RegisterType(reflect.TypeOf(Amount{}), nil)
+
+	got, err = InferSchema("avro", Invoice{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"total","type":"string"}`)
+
+	ClearSchemaCache()
+	_, ok = schemaCache.Load(newSchemaCacheKey(reflect.TypeOf(Invoice{}), newInferOptions([]Option{WithFallbackTag("avro")})))
+	assert.False(t, ok)
+
+	_, err = InferSchemaWithOptions(Invoice{}, WithoutCache())
+	assert.NoError(t, err)
+	_, ok = schemaCache.Load(newSchemaCacheKey(reflect.TypeOf(Invoice{}), newInferOptions([]Option{WithoutCache()})))
+	assert.False(t, ok)
+}
+
+func BenchmarkInferSchema(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := InferSchema("avro", Order{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := InferSchemaWithOptions(Order{}, WithFallbackTag("avro"), WithoutCache()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+type Deep struct {
+	Inner *DeepInner
+}
+
+type DeepInner struct {
+	Items []DeepItem
+}
+
+type DeepItem struct {
+	Values map[string]complex128
+}
+
+func TestInferSchema_error_path(t *testing.T) {
+	_, err := InferSchema("avro", Deep{})
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "infer schema: Deep.Inner.Items[].Values[]: unsupported type: complex128"), err.Error())
+	}
+
+	type Shallow struct {
+		Bad complex64
+	}
+
+	_, err = InferSchema("avro", &Shallow{})
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "infer schema: Shallow.Bad: unsupported type: complex64"), err.Error())
+	}
+}
+
+func TestInferSchema_unsupported_types(t *testing.T) {
+	type Channel struct {
+		Events chan string `avro:"events"`
+	}
+
+	_, err := InferSchema("avro", Channel{})
+	assert.EqualError(t, err, `infer schema: Channel.Events: unsupported type: chan has no avro equivalent, `+
+		`set the type with the type=, items= or values= tag option, or skip the field with avro:"-"`)
+
+	type Complex struct {
+		Point complex128 `avro:"point"`
+	}
+
+	_, err = InferSchema("avro", Complex{})
+	assert.EqualError(t, err, `infer schema: Complex.Point: unsupported type: complex128 has no avro equivalent, `+
+		`set the type with the type=, items= or values= tag option, or skip the field with avro:"-"`)
+
+	type Skipped struct {
+		Events   chan string `avro:"-"`
+		Callback func()      `avro:"callback,type=null"`
+	}
+
+	_, err = InferSchema("avro", Skipped{})
+	assert.NoError(t, err)
+}
+
+type Envelope struct {
+	Payload interface{} `avro:"payload,type=string|int|null"`
+}
+
+func TestInferSchema_interface_union(t *testing.T) {
+	got, err := InferSchema("avro", Envelope{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Envelope","type":"record","fields":[{"name":"payload","type":["string","int","null"]}]}`, got)
+
+	type Untyped struct {
+		Payload fmt.Stringer `avro:"payload"`
+	}
+
+	_, err = InferSchema("avro", Untyped{})
+	assert.EqualError(t, err, "infer schema: Untyped.Payload: interface has no structural type, an explicit union is required such as type=string|int|null")
+}
+
+type Overrides struct {
+	Home   Location  `avro:"home,name=Address"`
+	Work   *Location `avro:"work,name=Office"`
+	Color  Color     `avro:"color,enum=RED|GREEN|BLUE,name=Hue"`
+	Digest Digest    `avro:"digest,name=sha1"`
+	Total  big.Rat   `avro:"total,precision=10,size=8,name=Money"`
+}
+
+func TestInferSchema_name_option(t *testing.T) {
+	got, err := InferSchema("avro", Overrides{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Overrides","type":"record","fields":[`+
+		`{"name":"home","type":{"name":"Address","type":"record","fields":[{"name":"street","type":"string"}]}},`+
+		`{"name":"work","type":["null",{"name":"Office","type":"record","fields":[{"name":"street","type":"string"}]}],"default":null},`+
+		`{"name":"color","type":{"name":"Hue","type":"enum","symbols":["RED","GREEN","BLUE"]}},`+
+		`{"name":"digest","type":{"name":"sha1","type":"fixed","size":20}},`+
+		`{"name":"total","type":{"name":"Money","type":"fixed","size":8,"logicalType":"decimal","precision":10}}]}`, got)
+
+	type Qualified struct {
+		Home Location `avro:"home,name=com.acme.Address"`
+	}
+
+	_, err = InferSchema("avro", Qualified{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid type name "com.acme.Address" of home`)
+	}
+
+	// a name= override can't reuse the name of another named type
+	for _, tc := range []struct {
+		v    interface{}
+		want string
+	}{
+		{
+			v: struct {
+				Home Location `avro:"home"`
+				B    struct {
+					X int `avro:"x"`
+				} `avro:"b,name=Location"`
+			}{},
+			want: "B: name Location already defined by type github.com/leboncoin/avrocado.Location",
+		},
+		{
+			v: struct {
+				Color Color  `avro:"color,enum=RED|GREEN|BLUE,name=Hue"`
+				Shade string `avro:"shade,enum=LIGHT|DARK,name=Hue"`
+			}{},
+			want: "Shade: name Hue already defined by a different enum",
+		},
+		{
+			v: struct {
+				Digest Digest  `avro:"digest,name=sha1"`
+				Short  [8]byte `avro:"short,name=sha1"`
+			}{},
+			want: "Short: name sha1 already defined by a different fixed",
+		},
+	} {
+		_, err := InferSchemaWithOptions(tc.v, WithRecordName("Clash"))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tc.want)
+		}
+	}
+
+	// the same enum may be declared again, it is then referenced
+	type Palette struct {
+		Fill   Color `avro:"fill,enum=RED|GREEN|BLUE,name=Hue"`
+		Stroke Color `avro:"stroke,enum=RED|GREEN|BLUE,name=Hue"`
+	}
+
+	got, err = InferSchema("avro", Palette{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"stroke","type":"Hue"}`)
+}
+
+type Nested struct {
+	Tags     map[string][]int32            `avro:"tags"`
+	Rows     []map[string]string           `avro:"rows"`
+	Override map[string]interface{}        `avro:"override,values=array<null|string>"`
+	Matrix   []interface{}                 `avro:"matrix,items=map<array<long>>|null"`
+	Either   interface{}                   `avro:"either,type=null|array<string>"`
+	Deep     map[string][]map[string]int32 `avro:"deep"`
+}
+
+func TestInferSchema_nested_maps_and_arrays(t *testing.T) {
+	got, err := InferSchema("avro", Nested{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Nested","type":"record","fields":[`+
+		`{"name":"tags","type":{"type":"map","values":{"type":"array","items":"int"}}},`+
+		`{"name":"rows","type":{"type":"array","items":{"type":"map","values":"string"}}},`+
+		`{"name":"override","type":{"type":"map","values":{"type":"array","items":["null","string"]}}},`+
+		`{"name":"matrix","type":{"type":"array","items":[{"type":"map","values":{"type":"array","items":"long"}},"null"]}},`+
+		`{"name":"either","type":["null",{"type":"array","items":"string"}]},`+
+		`{"name":"deep","type":{"type":"map","values":{"type":"array","items":{"type":"map","values":"int"}}}}]}`, got)
+
+	type Unbalanced struct {
+		Bad []interface{} `avro:"bad,items=array<string"`
+	}
+
+	_, err = InferSchema("avro", Unbalanced{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unbalanced type expression "array<string"`)
+	}
+}
+
+func TestInferSchema_unknown_items_and_values(t *testing.T) {
+	type Typo struct {
+		Tags []interface{} `avro:"tags,items=null|strign"`
+	}
+
+	_, err := InferSchema("avro", Typo{})
+	assert.EqualError(t, err, `infer schema: Typo.Tags: items: unknown type "strign", must be a primitive type or the name of a named type`)
+
+	type NestedTypo struct {
+		Scores map[string]interface{} `avro:"scores,values=array<lnog>"`
+	}
+
+	_, err = InferSchema("avro", NestedTypo{})
+	assert.EqualError(t, err, `infer schema: NestedTypo.Scores: values: unknown type "lnog", must be a primitive type or the name of a named type`)
+
+	// the named types defined before, and the full names of those defined elsewhere, are referenced
+	type References struct {
+		Home    Location               `avro:"home"`
+		Visited []interface{}          `avro:"visited,items=Location"`
+		Billing map[string]interface{} `avro:"billing,values=com.acme.Address"`
+	}
+
+	got, err := InferSchemaWithOptions(References{}, WithNamespace("com.acme"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"visited","type":{"type":"array","items":"Location"}},`+
+		`{"name":"billing","type":{"type":"map","values":"com.acme.Address"}}`)
+}
+
+func TestInferSchema_duplicate_field_name(t *testing.T) {
+	_, err := InferSchemaWithOptions(Duplicated{}, WithFallbackTag("json"), WithNamespace("com.acme"))
+	assert.EqualError(t, err, `infer schema: duplicate field name "id" in record com.acme.Duplicated`)
+}
+
+type Duplicated struct {
+	ID   int64  `avro:"id"`
+	UUID string `json:"id"`
+}
+
+type Connection struct {
+	Remote  net.IP         `avro:"remote"`
+	Local   *net.IP        `avro:"local"`
+	Timeout time.Duration  `avro:"timeout"`
+	Retry   *time.Duration `avro:"retry"`
+}
+
+func TestInferSchema_net_ip_and_duration(t *testing.T) {
+	got, err := InferSchema("avro", Connection{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Connection","type":"record","fields":[`+
+		`{"name":"remote","type":"bytes"},`+
+		`{"name":"local","type":["null","bytes"],"default":null},`+
+		`{"name":"timeout","type":"long"},`+
+		`{"name":"retry","type":["null","long"],"default":null}]}`, got)
+
+	// the mappings can be overridden
+	RegisterType(reflect.TypeOf(net.IP{}), func() TypedSchema { return TypedSchema{Name: "ipv6", Type: "fixed", Size: 16} })
+	defer RegisterType(reflect.TypeOf(net.IP{}), func() TypedSchema { return TypedSchema{Type: "bytes"} })
+
+	got, err = InferSchema("avro", Connection{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"remote","type":{"name":"ipv6","type":"fixed","size":16}},{"name":"local","type":["null","ipv6"],"default":null}`)
+
+	retry := 2 * time.Second
+	want := Connection{Remote: net.ParseIP("::1"), Timeout: time.Minute, Retry: &retry}
+
+	schema, err := InferSchema("avro", want)
+	assert.NoError(t, err)
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var decoded Connection
+	assert.NoError(t, Unmarshal(schema, data, &decoded))
+	assert.Equal(t, want, decoded)
+}
+
+// GUID mimics github.com/google/uuid.UUID, which is written through its driver.Valuer and sql.Scanner implementations.
+type GUID [16]byte
+
+func (g GUID) Value() (driver.Value, error) {
+	return hex.EncodeToString(g[:]), nil
+}
+
+func (g *GUID) Scan(src interface{}) error {
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into GUID", src)
+	}
+
+	_, err := hex.Decode(g[:], []byte(s))
+
+	return err
+}
+
+type Account struct {
+	ID     GUID     `avro:"id"`
+	Ref    string   `avro:"ref,logicalType=uuid"`
+	Parent *string  `avro:"parent,logicalType=uuid"`
+	Raw    [16]byte `avro:"raw,logicalType=uuid"`
+}
+
+func TestInferSchema_uuid(t *testing.T) {
+	for _, name := range []string{"github.com/google/uuid.UUID", "github.com/gofrs/uuid.UUID"} {
+		fn, ok := typeRegistry.names[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, TypedSchema{Type: "string", LogicalType: "uuid"}, fn())
+		}
+	}
+
+	RegisterTypeName("github.com/leboncoin/avrocado.GUID", typeRegistry.names["github.com/google/uuid.UUID"])
+	defer RegisterTypeName("github.com/leboncoin/avrocado.GUID", nil)
+
+	schema, err := InferSchema("avro", Account{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Account","type":"record","fields":[`+
+		`{"name":"id","type":{"type":"string","logicalType":"uuid"}},`+
+		`{"name":"ref","type":{"type":"string","logicalType":"uuid"}},`+
+		`{"name":"parent","type":["null",{"type":"string","logicalType":"uuid"}],"default":null},`+
+		`{"name":"raw","type":{"name":"Account_raw_uuid","type":"fixed","size":16,"logicalType":"uuid"}}]}`, schema)
+
+	want := Account{ID: GUID{1, 2, 3}, Ref: "0b6b1f7a-4bb4-4d4b-9d8e-2f3c1f0e2a11", Raw: [16]byte{4, 5, 6}}
+
+	data, err := Marshal(schema, want)
+	assert.NoError(t, err)
+
+	var got Account
+	assert.NoError(t, Unmarshal(schema, data, &got))
+	assert.Equal(t, want, got)
+
+	type NotUUID struct {
+		ID int64 `avro:"id,logicalType=uuid"`
+	}
+
+	_, err = InferSchema("avro", NotUUID{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "uuid must be string based or a [16]byte, got int64")
+	}
+}
+
+type Nullables struct {
+	Items    []*Location          `avro:"items"`
+	List     *[]Location          `avro:"list"`
+	Values   map[string]*Location `avro:"values"`
+	Map      *map[string]Location `avro:"map"`
+	Strings  []*string            `avro:"strings"`
+	Pointers **string             `avro:"pointers"`
+	Nullable *sql.NullString      `avro:"nullable"`
+}
+
+func TestInferSchema_nested_nullability(t *testing.T) {
+	got, err := InferSchema("avro", Nullables{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Nullables","type":"record","fields":[`+
+		`{"name":"items","type":{"type":"array","items":["null",{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}]}},`+
+		`{"name":"list","type":["null",{"type":"array","items":"Location"}],"default":null},`+
+		`{"name":"values","type":{"type":"map","values":["null","Location"]}},`+
+		`{"name":"map","type":["null",{"type":"map","values":"Location"}],"default":null},`+
+		`{"name":"strings","type":{"type":"array","items":["null","string"]}},`+
+		`{"name":"pointers","type":["null","string"],"default":null},`+
+		`{"name":"nullable","type":["null","string"],"default":null}]}`, got)
+
+	got, err = InferSchemaWithOptions(Nullables{}, WithFallbackTag("avro"), WithNullLast())
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"items","type":{"type":"array","items":[{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]},"null"]}}`)
+	assert.Contains(t, got, `{"name":"list","type":[{"type":"array","items":"Location"},"null"]}`)
+}
+
+func TestInferSchemaWithOptions_name_mapper(t *testing.T) {
+	got, err := InferSchemaWithOptions(A{}, WithFallbackTag("avro"), WithNameMapper(strings.ToLower))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"A","type":"record","fields":[`+
+		`{"name":"b","type":"string"},`+
+		`{"name":"c","type":"`+platformInt+`"},`+
+		`{"name":"e","type":{"name":"E","type":"record","fields":[{"name":"f","type":"string"}]}}]}`, got)
+
+	got, err = InferSchemaWithOptions(A{}, WithFallbackTag("avro"), WithNameMapper(strings.ToUpper))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"b","type":"string"},{"name":"C"`)
+}
+
+type Tagged struct {
+	ID      int64  `avro:"id" json:"identifier" db:"user_id"`
+	Name    string `json:"name" db:"user_name"`
+	Email   string `db:"email"`
+	Created string `json:",omitempty" db:"created_at"`
+	Other   string
+}
+
+func TestInferSchemaWithOptions_fallback_tags(t *testing.T) {
+	got, err := InferSchemaWithOptions(Tagged{}, WithFallbackTags("json", "db"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Tagged","type":"record","fields":[`+
+		`{"name":"id","type":"long"},{"name":"name","type":"string"},{"name":"email","type":"string"},`+
+		`{"name":"created_at","type":"string"},{"name":"Other","type":"string"}]}`, got)
+
+	got, err = InferSchema("db", Tagged{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"user_name","type":"string"}`)
+}
+
+type Profile struct {
+	Name     string  `json:"name"`
+	Nickname string  `json:"nickname,omitempty"`
+	Age      *int32  `json:"age,omitempty"`
+	Bio      string  `avro:"bio" json:"bio,omitempty"`
+	Website  *string `json:",omitempty"`
+}
+
+func TestInferSchemaWithOptions_omitempty_nullable(t *testing.T) {
+	got, err := InferSchema("json", Profile{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"nickname","type":"string"}`)
+
+	got, err = InferSchemaWithOptions(Profile{}, WithFallbackTag("json"), WithOmitEmptyNullable())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Profile","type":"record","fields":[`+
+		`{"name":"name","type":"string"},`+
+		`{"name":"nickname","type":["null","string"],"default":null},`+
+		`{"name":"age","type":["null","int"],"default":null},`+
+		`{"name":"bio","type":"string"},`+
+		`{"name":"Website","type":["null","string"],"default":null}]}`, got)
+}
+
+type Preferences struct {
+	Volume   int32         `avro:"volume"`
+	Theme    string        `avro:"theme"`
+	Muted    bool          `avro:"muted"`
+	Ratio    float64       `avro:"ratio"`
+	Retries  int           `avro:"retries,default=3"`
+	Nickname *string       `avro:"nickname"`
+	Level    string        `avro:"level,enum=LOW|HIGH"`
+	Tags     []string      `avro:"tags"`
+	Home     Location      `avro:"home"`
+	Since    time.Time     `avro:"since"`
+	Timeout  time.Duration `avro:"timeout"`
+}
+
+func TestInferSchemaWithOptions_zero_defaults(t *testing.T) {
+	got, err := InferSchemaWithOptions(Preferences{}, WithZeroDefaults())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Preferences","type":"record","fields":[`+
+		`{"name":"volume","type":"int","default":0},`+
+		`{"name":"theme","type":"string","default":""},`+
+		`{"name":"muted","type":"boolean","default":false},`+
+		`{"name":"ratio","type":"double","default":0},`+
+		`{"name":"retries","type":"long","default":3},`+
+		`{"name":"nickname","type":["null","string"],"default":null},`+
+		`{"name":"level","type":{"name":"level","type":"enum","symbols":["LOW","HIGH"]}},`+
+		`{"name":"tags","type":{"type":"array","items":"string"}},`+
+		`{"name":"home","type":{"name":"Location","type":"record","fields":[{"name":"street","type":"string","default":""}]}},`+
+		`{"name":"since","type":{"type":"long","logicalType":"timestamp-millis"}},`+
+		`{"name":"timeout","type":"long","default":0}]}`, got)
+
+	// the zero values are read for the fields missing from the data
+	reader := `{"type":"record","name":"Preferences","fields":[` +
+		`{"name":"volume","type":"int","default":0},{"name":"theme","type":"string","default":""},` +
+		`{"name":"muted","type":"boolean","default":false}]}`
+	b, err := Marshal(`{"type":"record","name":"Preferences","fields":[]}`, Preferences{})
+	assert.NoError(t, err)
+
+	decoded := Preferences{Volume: 11, Theme: "dark", Muted: true}
+	assert.NoError(t, UnmarshalResolving(`{"type":"record","name":"Preferences","fields":[]}`, reader, b, &decoded))
+	assert.Equal(t, Preferences{}, decoded)
+
+	got, err = InferSchema("avro", Preferences{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"volume","type":"int"},`)
+}
+
+func TestInferSchema_invalid_names(t *testing.T) {
+	type Hyphenated struct {
+		UserID int64 `avro:"user-id"`
+	}
+
+	_, err := InferSchema("avro", Hyphenated{})
+	assert.EqualError(t, err, `infer schema: Hyphenated.UserID: invalid field name "user-id", must match ^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	type Digits struct {
+		Home Location `avro:"home,name=1st"`
+	}
+
+	_, err = InferSchema("avro", Digits{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `invalid type name "1st" of home`)
+	}
+
+	_, err = InferSchemaWithOptions(Location{}, WithNamespace("com.acme-corp"))
+	assert.EqualError(t, err, `infer schema: invalid namespace "com.acme-corp" of record Location`)
+
+	_, err = InferSchemaWithOptions(Location{}, WithNamespace("com..acme"))
+	assert.Error(t, err)
+
+	got, err := InferSchemaWithOptions(Location{}, WithNamespace("com.acme_corp.v2"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"namespace":"com.acme_corp.v2"`)
+
+	_, err = InferSchema("avro", struct {
+		Street string `avro:"street"`
+	}{})
+	assert.EqualError(t, err, "infer schema: anonymous struct has no record name, set one with WithRecordName")
+}
+
+type Coord struct {
+	X, Y int
+}
+
+func (c Coord) String() string {
+	return fmt.Sprintf("%d,%d", c.X, c.Y)
+}
+
+func (c *Coord) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d,%d", &c.X, &c.Y)
+	return err
+}
+
+type Grid struct {
+	Rows  map[int]string    `avro:"rows"`
+	Ports map[uint16]bool   `avro:"ports"`
+	Cells map[Coord]float64 `avro:"cells"`
+}
+
+func TestInferSchema_stringify_map_keys(t *testing.T) {
+	_, err := InferSchema("avro", Grid{})
+	assert.EqualError(t, err, "infer schema: Grid.Rows: map key must be string")
+
+	got, err := InferSchemaWithOptions(Grid{}, WithStringifyMapKeys())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Grid","type":"record","fields":[`+
+		`{"name":"rows","type":{"type":"map","values":"string"}},`+
+		`{"name":"ports","type":{"type":"map","values":"boolean"}},`+
+		`{"name":"cells","type":{"type":"map","values":"double"}}]}`, got)
+
+	type Unkeyed struct {
+		Values map[float64]string `avro:"values"`
+	}
+
+	_, err = InferSchemaWithOptions(Unkeyed{}, WithStringifyMapKeys())
+	assert.EqualError(t, err, "infer schema: Unkeyed.Values: map key must be a string, an integer or a fmt.Stringer, got float64")
+}
+
+var update = flag.Bool("update", false, "update the golden files")
+
+type Catalog struct {
+	Document
+	Shipment Shipment               `avro:"shipment"`
+	Palette  *Palette               `avro:"palette"`
+	Nested   Nested                 `avro:"nested"`
+	Envelope Envelope               `avro:"envelope"`
+	Defaults Defaults               `avro:"defaults"`
+	Index    map[string][]*Location `avro:"index"`
+}
+
+func TestInferSchema_stable_output(t *testing.T) {
+	golden := filepath.Join("testdata", "catalog.avsc")
+
+	got, err := InferSchemaWithOptions(Catalog{}, WithFallbackTag("avro"), WithIndent("  "), WithoutCache())
+	assert.NoError(t, err)
+
+	if *update {
+		assert.NoError(t, os.WriteFile(golden, []byte(got+"\n"), 0o644))
+	}
+
+	want, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), got+"\n")
+
+	for i := 0; i < 50; i++ {
+		again, err := InferSchemaWithOptions(Catalog{}, WithFallbackTag("avro"), WithIndent("  "), WithoutCache())
+		assert.NoError(t, err)
+
+		if !assert.Equal(t, got, again, "run %d", i) {
+			return
+		}
+	}
+}
+
+type Pinned struct {
+	Hash     [16]byte `avro:"hash,schema='{\"type\": \"fixed\", \"name\": \"MD5\", \"size\": 16}'"`
+	Amount   string   `avro:"amount,schema=eyJ0eXBlIjoic3RyaW5nIiwibG9naWNhbFR5cGUiOiJ1dWlkIn0="`
+	Comment  *string  `avro:"comment,schema='[\"null\",\"string\"]',default=null"`
+	Priority int32    `avro:"priority"`
+}
+
+func TestInferSchema_schema_override(t *testing.T) {
+	got, err := InferSchema("avro", Pinned{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Pinned","type":"record","fields":[`+
+		`{"name":"hash","type":{"type":"fixed","name":"MD5","size":16}},`+
+		`{"name":"amount","type":{"type":"string","logicalType":"uuid"}},`+
+		`{"name":"comment","type":["null","string"],"default":null},`+
+		`{"name":"priority","type":"int"}]}`, got)
+
+	_, err = Marshal(got, Pinned{Amount: "4d3b0a2c-6f82-4d1e-9d43-5b0c7a0f6e21", Priority: 1})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		tag      reflect.StructTag
+		expected string
+	}{
+		{`avro:"hash,schema='{\"type\":'"`, `schema {"type": isn't well-formed JSON: unexpected end of JSON input`},
+		{`avro:"hash,schema='{\"size\":16}'"`, `schema {"size":16} without a type`},
+		{`avro:"hash,schema='16'"`, `schema 16 must be a type name, a union or an object`},
+		{`avro:"hash,schema='{\"type\":\"string\"}"`, `unterminated schema '{"type":"string"}`},
+		{`avro:"hash,schema=not base64"`, `schema must be single-quoted or base64 encoded JSON: illegal base64 data at input byte 3`},
+	} {
+		_, _, _, err := (&inferrer{}).fieldTag(reflect.StructField{Name: "Hash", Tag: tc.tag})
+		assert.EqualError(t, err, tc.expected, string(tc.tag))
+	}
+
+	type Conflicting struct {
+		Hash string `avro:"hash,type=string,schema='\"string\"'"`
+	}
+
+	_, err = InferSchema("avro", Conflicting{})
+	assert.EqualError(t, err, "infer schema: Conflicting.Hash: schema and type of hash are exclusive")
+}
+
+// Day is a calendar date, as civil.Date of the Google Cloud libraries.
+type Day struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func (d Day) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)), nil
+}
+
+func (d *Day) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01-02", string(text))
+	if err != nil {
+		return err
+	}
+
+	d.Year, d.Month, d.Day = t.Date()
+
+	return nil
+}
+
+type Opening struct {
+	Date    time.Time     `avro:"date,logicalType=date"`
+	Opens   time.Time     `avro:"opens,logicalType=time-millis"`
+	Closes  time.Duration `avro:"closes"`
+	Holiday Day           `avro:"holiday,logicalType=date"`
+	Micros  *time.Time    `avro:"micros,logicalType=time-micros"`
+}
+
+func TestInferSchema_dates_and_times_of_day(t *testing.T) {
+	got, err := InferSchema("avro", Opening{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Opening","type":"record","fields":[`+
+		`{"name":"date","type":{"type":"int","logicalType":"date"}},`+
+		`{"name":"opens","type":{"type":"int","logicalType":"time-millis"}},`+
+		`{"name":"closes","type":"long"},`+
+		`{"name":"holiday","type":{"type":"int","logicalType":"date"}},`+
+		`{"name":"micros","type":["null",{"type":"long","logicalType":"time-micros"}],"default":null}]}`, got)
+
+	type Untagged struct {
+		Holiday Day `avro:"holiday"`
+	}
+
+	got, err = InferSchema("avro", Untagged{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"type":{"name":"Day","type":"record"`)
+
+	type BadTime struct {
+		Date time.Time `avro:"date,logicalType=local-timestamp-millis"`
+	}
+
+	_, err = InferSchema("avro", BadTime{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unsupported logical type for time.Time: local-timestamp-millis")
+	}
+}
+
+func TestInferSchemaWithOptions_record_name(t *testing.T) {
+	root := struct {
+		Street string `avro:"street"`
+		Geo    struct {
+			Lat float64 `avro:"lat"`
+		} `avro:"geo,name=Geo"`
+	}{}
+
+	got, err := InferSchemaWithOptions(root, WithRecordName("Root"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Root","type":"record","fields":[`+
+		`{"name":"street","type":"string"},`+
+		`{"name":"geo","type":{"name":"Geo","type":"record","fields":[{"name":"lat","type":"double"}]}}]}`, got)
+
+	got, err = InferSchemaWithOptions(Location{}, WithRecordName("Address"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Address","type":"record","fields":[{"name":"street","type":"string"}]}`, got)
+
+	_, err = InferSchemaWithOptions(root, WithRecordName("my-root"))
+	assert.EqualError(t, err, `infer schema: invalid record name "my-root", must match ^[A-Za-z_][A-Za-z0-9_]*$`)
+
+}
+
+func TestInferSchema_anonymous_nested_structs(t *testing.T) {
+	type Unnamed struct {
+		Geo struct {
+			Lat float64 `avro:"lat"`
+		} `avro:"geo"`
+		Meta *struct {
+			Source string `avro:"source"`
+		} `avro:"meta_data,name=Metadata"`
+	}
+
+	got, err := InferSchema("avro", Unnamed{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Unnamed","type":"record","fields":[`+
+		`{"name":"geo","type":{"name":"Geo","type":"record","fields":[{"name":"lat","type":"double"}]}},`+
+		`{"name":"meta_data","type":["null",{"name":"Metadata","type":"record","fields":[{"name":"source","type":"string"}]}],"default":null}]}`, got)
+
+	type Clashed struct {
+		Home     Location `avro:"home"`
+		Location struct {
+			City string `avro:"city"`
+		} `avro:"location"`
+	}
+
+	_, err = InferSchema("avro", Clashed{})
+	assert.EqualError(t, err, "infer schema: Clashed.Location: anonymous struct of location is named Location, "+
+		"which is already defined, set another name with the name= tag option")
+}
+
+// IPNet shares the name of net.IPNet.
+type IPNet struct {
+	Zone int `avro:"zone"`
+}
+
+func TestInferSchema_same_named_types(t *testing.T) {
+	type Route struct {
+		Local  net.IPNet `avro:"local"`
+		Remote IPNet     `avro:"remote"`
+	}
+
+	_, err := InferSchema("avro", Route{})
+	assert.EqualError(t, err, "infer schema: Route.Remote: name IPNet already defined by type net.IPNet")
+
+	type Routes struct {
+		Local  IPNet  `avro:"local"`
+		Remote *IPNet `avro:"remote"`
+	}
+
+	got, err := InferSchema("avro", Routes{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Routes","type":"record","fields":[`+
+		`{"name":"local","type":{"name":"IPNet","type":"record","fields":[{"name":"zone","type":"long"}]}},`+
+		`{"name":"remote","type":["null","IPNet"],"default":null}]}`, got)
+}
+
+type Ledger struct {
+	Count   big.Int    `avro:"count"`
+	Balance *big.Int   `avro:"balance"`
+	Exact   big.Int    `avro:"exact,precision=30"`
+	Ratio   big.Float  `avro:"ratio"`
+	Rate    *big.Float `avro:"rate"`
+}
+
+func TestInferSchema_big_numbers(t *testing.T) {
+	got, err := InferSchema("avro", Ledger{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Ledger","type":"record","fields":[`+
+		`{"name":"count","type":"long"},`+
+		`{"name":"balance","type":["null","long"],"default":null},`+
+		`{"name":"exact","type":{"type":"bytes","logicalType":"decimal","precision":30}},`+
+		`{"name":"ratio","type":"double"},`+
+		`{"name":"rate","type":["null","double"],"default":null}]}`, got)
+
+	// the default mapping can be overridden
+	RegisterType(reflect.TypeOf(big.Float{}), func() TypedSchema { return TypedSchema{Type: "float"} })
+	defer RegisterType(reflect.TypeOf(big.Float{}), func() TypedSchema { return TypedSchema{Type: "double"} })
+
+	got, err = InferSchema("avro", Ledger{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"ratio","type":"float"}`)
+}
+
+func TestInferSchema_invalid_unions(t *testing.T) {
+	type Duplicates struct {
+		ID     interface{}   `avro:"id,type=string|int|string"`
+		Lists  interface{}   `avro:"lists,type=array<int>|array<string>"`
+		Items  []interface{} `avro:"items,items=null|long|null"`
+		Places interface{}   `avro:"places,type=null|Location|Location"`
+	}
+
+	for field, expected := range map[string]string{
+		"ID":     "type of id: union contains string twice",
+		"Lists":  "type of lists: union contains array twice",
+		"Items":  "items: union contains null twice",
+		"Places": "type of places: union contains Location twice",
+	} {
+		f, _ := reflect.TypeOf(Duplicates{}).FieldByName(field)
+		typ := reflect.StructOf([]reflect.StructField{f})
+
+		_, err := (&inferrer{named: make(map[string]definition), visiting: make(map[reflect.Type]bool)}).inferFields(typ, "", "", 0)
+		if assert.Error(t, err, field) {
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+
+	got, err := InferSchemaFor[struct {
+		Value interface{} `avro:"value,type=null|int|Location|com.acme.Location"`
+	}]("avro", WithRecordName("Distinct"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"type":["null","int","Location","com.acme.Location"]`)
+
+	type Unflattened struct{}
+
+	RegisterType(reflect.TypeOf(Unflattened{}), func() TypedSchema {
+		return TypedSchema{Type: []interface{}{"null", []interface{}{"int", "string"}}}
+	})
+	defer RegisterType(reflect.TypeOf(Unflattened{}), nil)
+
+	type Wrapping struct {
+		Value Unflattened `avro:"value"`
+	}
+
+	_, err = InferSchema("avro", Wrapping{})
+	assert.EqualError(t, err, "infer schema: Wrapping.Value: registered schema of avro.Unflattened: union can't immediately contain another union")
+}
+
+type Widened struct {
+	Counter int32   `avro:"counter,type=long"`
+	Ratio   float32 `avro:"ratio,type=double"`
+	Hits    []int32 `avro:"hits,items=long"`
+}
+
+func TestInferSchema_single_type_option(t *testing.T) {
+	got, err := InferSchema("avro", Widened{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Widened","type":"record","fields":[`+
+		`{"name":"counter","type":"long"},`+
+		`{"name":"ratio","type":"double"},`+
+		`{"name":"hits","type":{"type":"array","items":"long"}}]}`, got)
+
+	b, err := Marshal(got, Widened{Counter: 7, Ratio: 0.5, Hits: []int32{1}})
+	assert.NoError(t, err)
+
+	var decoded Widened
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, Widened{Counter: 7, Ratio: 0.5, Hits: []int32{1}}, decoded)
+}
+
+type Client struct {
+	_    struct{} `avro:",namespace=com.acme.crm,aliases=Customer|com.legacy.Buyer" avrodoc:"A client, billed monthly"`
+	Name string   `avro:"name"`
+	Home Location `avro:"home"`
+}
+
+type Contract struct {
+	Client Client `avro:"client"`
+}
+
+func TestInferSchema_record_options(t *testing.T) {
+	got, err := InferSchemaWithOptions(Contract{}, WithFallbackTag("avro"), WithNamespace("com.acme"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Contract","namespace":"com.acme","type":"record","fields":[`+
+		`{"name":"client","type":{"name":"Client","namespace":"com.acme.crm","doc":"A client, billed monthly","aliases":["Customer","com.legacy.Buyer"],"type":"record","fields":[`+
+		`{"name":"name","type":"string"},`+
+		`{"name":"home","type":{"name":"Location","namespace":"com.acme.crm","type":"record","fields":[{"name":"street","type":"string"}]}}]}}]}`, got)
+
+	// the options of the inference win for the top-level record
+	got, err = InferSchemaWithOptions(Client{}, WithFallbackTag("avro"), WithDoc("Overridden"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"Client","namespace":"com.acme.crm","doc":"Overridden","aliases":["Customer","com.legacy.Buyer"]`)
+
+	type BadClient struct {
+		_ struct{} `avro:",aliases=old-client"`
+	}
+
+	_, err = InferSchema("avro", BadClient{})
+	assert.EqualError(t, err, `infer schema: invalid alias "old-client" of record BadClient`)
+}
+
+func TestInferSchema_top_level_pointers_and_slices(t *testing.T) {
+	record := `{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}`
+
+	got, err := InferSchema("avro", &Location{})
+	assert.NoError(t, err)
+	assert.Equal(t, record, got)
+
+	got, err = InferSchemaFor[**Location]("avro")
+	assert.NoError(t, err)
+	assert.Equal(t, record, got)
+
+	got, err = InferSchema("avro", []Location{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"array","items":`+record+`}`, got)
+
+	// the pointers nested in the top-level type remain nullable
+	got, err = InferSchema("avro", &[]*Location{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"array","items":["null",`+record+`]}`, got)
+
+	b, err := Marshal(got, []*Location{{Street: "Main"}, nil})
+	assert.NoError(t, err)
+
+	var decoded []*Location
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, []*Location{{Street: "Main"}, nil}, decoded)
+}
+
+type Charge struct {
+	_      struct{} `avro:",props=x-team:payments"`
+	Amount int64    `avro:"amount,props=x-pii:false|x-unit:cents"`
+	Card   string   `avro:"card,props=x-pii:true"`
+}
+
+func TestInferSchema_props(t *testing.T) {
+	got, err := InferSchema("avro", Charge{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Charge","type":"record","fields":[`+
+		`{"name":"amount","type":"long","x-pii":false,"x-unit":"cents"},`+
+		`{"name":"card","type":"string","x-pii":true}],"x-team":"payments"}`, got)
+
+	type badProp struct {
+		ID string `avro:"id,props=x-team"`
+	}
+	_, err = (&inferrer{}).inferFields(reflect.TypeOf(badProp{}), "", "", 0)
+	assert.EqualError(t, err, `ID: prop "x-team" must be written as key:value`)
+
+	type standardProp struct {
+		ID string `avro:"id,props=doc:an id"`
+	}
+	_, err = (&inferrer{}).inferFields(reflect.TypeOf(standardProp{}), "", "", 0)
+	assert.EqualError(t, err, "ID: prop doc is a standard attribute")
+}
+
+type Employee struct {
+	Name  string `avro:"name"`
+	SSN   string `avro:"ssn,pii,deprecated"`
+	Email string `avro:"email,pii,props=x-team:hr"`
+}
+
+func TestInferSchema_pii_and_deprecated_flags(t *testing.T) {
+	got, err := InferSchema("avro", Employee{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Employee","type":"record","fields":[`+
+		`{"name":"name","type":"string"},`+
+		`{"name":"ssn","type":"string","x-deprecated":true,"x-pii":true},`+
+		`{"name":"email","type":"string","x-pii":true,"x-team":"hr"}]}`, got)
+
+	got, err = InferSchemaWithOptions(Employee{}, WithFallbackTag("avro"), WithFlagProps("sensitive", "obsolete"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Employee","type":"record","fields":[`+
+		`{"name":"name","type":"string"},`+
+		`{"name":"ssn","type":"string","obsolete":true,"sensitive":true},`+
+		`{"name":"email","type":"string","sensitive":true,"x-team":"hr"}]}`, got)
+}
+
+type Job struct {
+	ID      string `avro:"id"`
+	Failure error  `avro:"failure"`
+	Cause   error  `avro:"cause,type=string"`
+}
+
+func TestInferSchema_error_fields(t *testing.T) {
+	got, err := InferSchema("avro", Job{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Job","type":"record","fields":[`+
+		`{"name":"id","type":"string"},`+
+		`{"name":"failure","type":["null","string"],"default":null},`+
+		`{"name":"cause","type":"string"}]}`, got)
+
+	_, err = InferSchemaWithOptions(Job{}, WithFallbackTag("avro"), WithStrictErrors())
+	assert.EqualError(t, err, "infer schema: Job.Failure: interface has no structural type, an explicit union is required such as type=string|int|null")
+
+	RegisterType(errorType, func() TypedSchema {
+		return TypedSchema{Type: []interface{}{"null", TypedSchema{Type: "string", LogicalType: "error-message"}}}
+	})
+	defer RegisterType(errorType, nil)
+
+	got, err = InferSchema("avro", Job{})
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"failure","type":["null",{"type":"string","logicalType":"error-message"}],"default":null}`)
+}
+
+type Maybe[T any] struct {
+	Value T
+	Set   bool
+}
+
+type Member struct {
+	Age      Maybe[int]      `avro:"age"`
+	Nickname Maybe[string]   `avro:"nickname"`
+	Home     Maybe[Location] `avro:"home"`
+}
+
+func TestRegisterOptional(t *testing.T) {
+	// unregistered, an instantiation is a record named after its type arguments, which avro doesn't allow
+	_, err := InferSchema("avro", Member{})
+	assert.EqualError(t, err, `infer schema: Member.Age: invalid record name "Maybe[int]", must match ^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	RegisterOptional("github.com/leboncoin/avrocado.Maybe", "Value", "Set")
+	defer RegisterOptional("github.com/leboncoin/avrocado.Maybe", "", "")
+
+	got, err := InferSchema("avro", Member{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Member","type":"record","fields":[`+
+		`{"name":"age","type":["null","long"],"default":null},`+
+		`{"name":"nickname","type":["null","string"],"default":null},`+
+		`{"name":"home","type":["null",{"name":"Location","type":"record","fields":[{"name":"street","type":"string"}]}],"default":null}]}`, got)
+
+	got, err = InferSchemaFor[Maybe[int]]("avro")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":["null","long"]}`, got)
+
+	RegisterOptional("github.com/leboncoin/avrocado.Maybe", "Value", "Present")
+
+	_, err = InferSchema("avro", Member{})
+	assert.EqualError(t, err, "infer schema: Member.Age: optional avro.Maybe[int] has no exported bool field Present")
+}
+
+type Timeline struct {
+	Events   []time.Time            `avro:"events"`
+	Optional []*time.Time           `avro:"optional"`
+	ByName   map[string]time.Time   `avro:"by_name"`
+	Precise  []time.Time            `avro:"precise,logicalType=timestamp-micros"`
+	Days     map[string][]time.Time `avro:"days,logicalType=date"`
+	Amounts  []big.Rat              `avro:"amounts,precision=10,scale=2"`
+}
+
+func TestInferSchema_times_in_collections(t *testing.T) {
+	got, err := InferSchema("avro", Timeline{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Timeline","type":"record","fields":[`+
+		`{"name":"events","type":{"type":"array","items":{"type":"long","logicalType":"timestamp-millis"}}},`+
+		`{"name":"optional","type":{"type":"array","items":["null",{"type":"long","logicalType":"timestamp-millis"}]}},`+
+		`{"name":"by_name","type":{"type":"map","values":{"type":"long","logicalType":"timestamp-millis"}}},`+
+		`{"name":"precise","type":{"type":"array","items":{"type":"long","logicalType":"timestamp-micros"}}},`+
+		`{"name":"days","type":{"type":"map","values":{"type":"array","items":{"type":"int","logicalType":"date"}}}},`+
+		`{"name":"amounts","type":{"type":"array","items":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}}}]}`, got)
+
+	at := time.Date(2024, 5, 1, 8, 9, 10, 123456789, time.UTC)
+	millis, day := at.Truncate(time.Millisecond), time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	timeline := Timeline{
+		Events:   []time.Time{at},
+		Optional: []*time.Time{nil, &at},
+		ByName:   map[string]time.Time{"launch": at},
+		Precise:  []time.Time{at},
+		Days:     map[string][]time.Time{"may": {day}},
+		Amounts:  []big.Rat{*big.NewRat(995, 100)},
+	}
+
+	b, err := Marshal(got, timeline)
+	assert.NoError(t, err)
+
+	var decoded Timeline
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, []time.Time{millis}, decoded.Events)
+	assert.Equal(t, []*time.Time{nil, &millis}, decoded.Optional)
+	assert.Equal(t, map[string]time.Time{"launch": millis}, decoded.ByName)
+	assert.Equal(t, []time.Time{at.Truncate(time.Microsecond)}, decoded.Precise)
+	assert.Equal(t, map[string][]time.Time{"may": {day}}, decoded.Days)
+	assert.Equal(t, "199/20", decoded.Amounts[0].RatString())
+}
+
+// Shape is a sum type of Circle and Square, which have the same fields.
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Size float64 `avro:"size"`
+}
+
+func (c Circle) Area() float64 { return math.Pi * c.Size * c.Size }
+
+type Square struct {
+	Size float64 `avro:"size"`
+}
+
+func (s *Square) Area() float64 { return s.Size * s.Size }
+
+type Drawing struct {
+	Main   Shape   `avro:"main"`
+	Extra  *Shape  `avro:"extra"`
+	Shapes []Shape `avro:"shapes"`
+}
+
+func TestRegisterUnion(t *testing.T) {
+	_, err := InferSchema("avro", Drawing{})
+	assert.EqualError(t, err, "infer schema: Drawing.Main: interface has no structural type, an explicit union is required such as type=string|int|null")
+
+	RegisterUnion(reflect.TypeOf((*Shape)(nil)).Elem(), Circle{}, (*Square)(nil))
+	defer RegisterUnion(reflect.TypeOf((*Shape)(nil)).Elem())
+
+	got, err := InferSchema("avro", Drawing{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Drawing","type":"record","fields":[`+
+		`{"name":"main","type":[{"name":"Circle","type":"record","fields":[{"name":"size","type":"double"}]},`+
+		`{"name":"Square","type":"record","fields":[{"name":"size","type":"double"}]}]},`+
+		`{"name":"extra","type":["null","Circle","Square"],"default":null},`+
+		`{"name":"shapes","type":{"type":"array","items":["Circle","Square"]}}]}`, got)
+
+	var extra Shape = Circle{Size: 1}
+	drawing := Drawing{Main: &Square{Size: 2}, Extra: &extra, Shapes: []Shape{&Square{Size: 3}, Circle{Size: 4}}}
+
+	b, err := Marshal(got, drawing)
+	assert.NoError(t, err)
+
+	var decoded Drawing
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, drawing, decoded)
+
+	drawing.Extra = nil
+	b, err = Marshal(got, drawing)
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Nil(t, decoded.Extra)
+
+	assert.Panics(t, func() { RegisterUnion(reflect.TypeOf(Circle{}), Circle{}) })
+	assert.Panics(t, func() { RegisterUnion(reflect.TypeOf((*Shape)(nil)).Elem(), Square{}) })
+}
+
+type Schedule struct {
+	Start  *time.Time `avro:"start"`
+	Micros *time.Time `avro:"micros,logicalType=timestamp-micros"`
+	Day    *time.Time `avro:"day,logicalType=date"`
+	Budget *big.Rat   `avro:"budget,precision=10,scale=2"`
+}
+
+func TestInferSchema_logical_types_in_unions(t *testing.T) {
+	got, err := InferSchema("avro", Schedule{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Schedule","type":"record","fields":[`+
+		`{"name":"start","type":["null",{"type":"long","logicalType":"timestamp-millis"}],"default":null},`+
+		`{"name":"micros","type":["null",{"type":"long","logicalType":"timestamp-micros"}],"default":null},`+
+		`{"name":"day","type":["null",{"type":"int","logicalType":"date"}],"default":null},`+
+		`{"name":"budget","type":["null",{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}],"default":null}]}`, got)
+
+	got, err = InferSchemaWithOptions(Schedule{}, WithFallbackTag("avro"), WithNullLast())
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"start","type":[{"type":"long","logicalType":"timestamp-millis"},"null"]}`)
+}
+
+type Subscriber struct {
+	ID      int64             `meta:"id"`
+	Email   *string           `meta:"email,doc=The contact address"`
+	Scores  []interface{}     `meta:"scores,items=null|double"`
+	Labels  map[string]string `meta:"labels,values=string" json:"tags"`
+	Comment string            `json:"comment" avro:"ignored"`
+}
+
+func TestInferSchema_tag_key(t *testing.T) {
+	got, err := InferSchemaWithOptions(Subscriber{}, WithTagKey("meta"), WithFallbackTag("json"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Subscriber","type":"record","fields":[`+
+		`{"name":"id","type":"long"},`+
+		`{"name":"email","doc":"The contact address","type":["null","string"],"default":null},`+
+		`{"name":"scores","type":{"type":"array","items":["null","double"]}},`+
+		`{"name":"labels","type":{"type":"map","values":"string"}},`+
+		`{"name":"comment","type":"string"}]}`, got)
+}
+
+type Onboarded struct {
+	ID      string           `avro:"id"`
+	Handler func()           `avro:"handler"`
+	Payload fmt.Stringer     `avro:"payload"`
+	Lines   []OnboardedLine  `avro:"lines"`
+	Counts  map[int]int32    `avro:"counts"`
+	Color   Color            `avro:"color,enum=RED|light-green"`
+	Parts   map[string]Audit `avro:"parts"`
+}
+
+type OnboardedLine struct {
+	Done chan bool `avro:"done"`
+	Note string    `avro:"note,order=sideways"`
+}
+
+func TestInferSchema_collect_errors(t *testing.T) {
+	_, err := InferSchema("avro", Onboarded{})
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "\n")
+	}
+
+	unsupported := `has no avro equivalent, set the type with the type=, items= or values= tag option, or skip the field with avro:"-"`
+
+	_, err = InferSchemaWithOptions(Onboarded{}, WithFallbackTag("avro"), WithCollectErrors())
+	assert.EqualError(t, err, "infer schema: "+strings.Join([]string{
+		"Onboarded.Handler: unsupported type: func " + unsupported,
+		"Onboarded.Payload: interface has no structural type, an explicit union is required such as type=string|int|null",
+		"Onboarded.Lines[].Done: unsupported type: chan " + unsupported,
+		`Onboarded.Lines[].Note: invalid order "sideways" of note, must be one of ascending, descending or ignore`,
+		"Onboarded.Counts: map key must be string",
+		`Onboarded.Color: invalid enum symbol: "light-green"`,
+	}, "\n"))
+
+	_, err = InferSchemaWithOptions(Location{}, WithFallbackTag("avro"), WithCollectErrors())
+	assert.NoError(t, err)
+}
+
+type SHA256 [32]byte
+
+type Artifact struct {
+	Digest    SHA256   `avro:"digest"`
+	Signed    *SHA256  `avro:"signed"`
+	Salt      [32]byte `avro:"salt"`
+	Pepper    [32]byte `avro:"pepper"`
+	Signature [64]byte `avro:"signature"`
+}
+
+func TestInferSchema_named_fixed(t *testing.T) {
+	got, err := InferSchemaWithOptions(Artifact{}, WithFallbackTag("avro"), WithNamespace("com.acme"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Artifact","namespace":"com.acme","type":"record","fields":[`+
+		`{"name":"digest","type":{"name":"SHA256","namespace":"com.acme","type":"fixed","size":32}},`+
+		`{"name":"signed","type":["null","com.acme.SHA256"],"default":null},`+
+		`{"name":"salt","type":{"name":"Artifact_salt_fixed","namespace":"com.acme","type":"fixed","size":32}},`+
+		`{"name":"pepper","type":{"name":"Artifact_pepper_fixed","namespace":"com.acme","type":"fixed","size":32}},`+
+		`{"name":"signature","type":{"name":"Artifact_signature_fixed","namespace":"com.acme","type":"fixed","size":64}}]}`, got)
+
+	sum := SHA256{1, 2, 3}
+	b, err := Marshal(got, Artifact{Digest: sum, Signed: &sum})
+	assert.NoError(t, err)
+
+	var decoded Artifact
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, Artifact{Digest: sum, Signed: &sum}, decoded)
+}
+
+type Keyring struct {
+	Primary [8]byte            `avro:"primary"`
+	Backup  *[8]byte           `avro:"backup"`
+	Rotated map[string][8]byte `avro:"rotated"`
+	Parent  *Keyring           `avro:"parent"`
+}
+
+func TestInferSchema_generated_names(t *testing.T) {
+	want := `{"name":"Keyring","type":"record","fields":[` +
+		`{"name":"primary","type":{"name":"Keyring_primary_fixed","type":"fixed","size":8}},` +
+		`{"name":"backup","type":["null",{"name":"Keyring_backup_fixed","type":"fixed","size":8}],"default":null},` +
+		`{"name":"rotated","type":{"type":"map","values":{"name":"Keyring_rotated_values_fixed","type":"fixed","size":8}}},` +
+		`{"name":"parent","type":["null","Keyring"],"default":null}]}`
+
+	// the names don't depend on the inferences made before
+	for i := 0; i < 2; i++ {
+		got, err := InferSchemaWithOptions(Keyring{}, WithFallbackTag("avro"), WithoutCache())
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	// the fixeds of the same field name in different records are distinct
+	got, err := InferSchemaWithOptions(struct {
+		Keyring Keyring `avro:"keyring"`
+		Primary [8]byte `avro:"primary"`
+	}{}, WithRecordName("Vault"))
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"primary","type":{"name":"Vault_primary_fixed","type":"fixed","size":8}}`)
+	assert.Contains(t, got, `{"name":"primary","type":{"name":"Keyring_primary_fixed","type":"fixed","size":8}}`)
+}
+
+type Quote struct {
+	Net   big.Rat  `avro:"net,precision=10,scale=2,backing=bytes"`
+	Gross big.Rat  `avro:"gross,precision=38,scale=2,backing=fixed,size=16"`
+	Tax   *big.Rat `avro:"tax,precision=18,scale=4,backing=fixed,size=8"`
+	Count big.Int  `avro:"count,precision=20"`
+}
+
+func TestInferSchema_decimal_backing(t *testing.T) {
+	got, err := InferSchema("avro", Quote{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Quote","type":"record","fields":[`+
+		`{"name":"net","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},`+
+		`{"name":"gross","type":{"name":"Quote_gross_decimal","type":"fixed","size":16,"logicalType":"decimal","precision":38,"scale":2}},`+
+		`{"name":"tax","type":["null",{"name":"Quote_tax_decimal","type":"fixed","size":8,"logicalType":"decimal","precision":18,"scale":4}],"default":null},`+
+		`{"name":"count","type":{"type":"bytes","logicalType":"decimal","precision":20}}]}`, got)
+
+	tax := big.NewRat(-1234, 100)
+	invoice := Quote{Net: *big.NewRat(995, 100), Gross: *big.NewRat(1194, 100), Tax: tax, Count: *big.NewInt(3)}
+	b, err := Marshal(got, invoice)
+	assert.NoError(t, err)
+
+	var decoded Quote
+	assert.NoError(t, Unmarshal(got, b, &decoded))
+	assert.Equal(t, 0, decoded.Gross.Cmp(&invoice.Gross))
+	assert.Equal(t, 0, decoded.Tax.Cmp(tax))
+
+	tests := []struct {
+		tag     string
+		wantErr string
+	}{
+		{`avro:"v,precision=39,backing=fixed,size=16"`, "fixed size 16 can't hold a decimal of precision 39, at most 38"},
+		{`avro:"v,precision=3,size=1"`, "fixed size 1 can't hold a decimal of precision 3, at most 2"},
+		{`avro:"v,precision=10,backing=fixed"`, "a fixed backed decimal requires a size"},
+		{`avro:"v,precision=10,backing=bytes,size=8"`, "a bytes backed decimal has no size"},
+		{`avro:"v,precision=10,backing=string"`, `invalid decimal backing "string", must be bytes or fixed`},
+		{`avro:"v,backing=fixed,size=8"`, "decimal requires a precision"},
+	}
+
+	for _, tt := range tests {
+		v := reflect.New(reflect.StructOf([]reflect.StructField{
+			{Name: "V", Type: bigIntType, Tag: reflect.StructTag(tt.tag)},
+		})).Elem().Interface()
+
+		_, err := InferSchemaWithOptions(v, WithRecordName("Invalid"))
+		if assert.Error(t, err, tt.tag) {
+			assert.Contains(t, err.Error(), tt.wantErr, tt.tag)
+		}
+	}
+}
+
+type Ticket struct {
+	Status Color  `avro:"status,enum=OPEN|CLOSED|UNKNOWN,enumdefault=UNKNOWN,default=OPEN"`
+	Level  string `avro:"level,enum=LOW|HIGH"`
+}
+
+func TestInferSchema_enum_default_symbol(t *testing.T) {
+	got, err := InferSchema("avro", Ticket{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Ticket","type":"record","fields":[`+
+		`{"name":"status","type":{"name":"Color","type":"enum","symbols":["OPEN","CLOSED","UNKNOWN"],"default":"UNKNOWN"},"default":"OPEN"},`+
+		`{"name":"level","type":{"name":"level","type":"enum","symbols":["LOW","HIGH"]}}]}`, got)
+
+	// the readers fall back to the default symbol for the symbols they don't know
+	writer := `{"type":"enum","name":"Color","symbols":["OPEN","CLOSED","REOPENED"]}`
+	reader := `{"type":"enum","name":"Color","symbols":["OPEN","CLOSED","UNKNOWN"],"default":"UNKNOWN"}`
+	assert.NoError(t, CheckCompatibility(reader, writer, CompatBackward))
+
+	type BadDefault struct {
+		Status Color `avro:"status,enum=OPEN|CLOSED,enumdefault=OTHER"`
+	}
+
+	_, err = InferSchema("avro", BadDefault{})
+	assert.EqualError(t, err, `infer schema: BadDefault.Status: enum default symbol "OTHER" is not one of its symbols`)
+}
+
+type Subscription struct {
+	Owner   interface{} `avro:"owner,ref=com.acme.crm.Client"`
+	Billing *Location   `avro:"billing,ref=com.acme.Address"`
+	Tier    string      `avro:"tier,ref=Tier,default=\"GOLD\""`
+}
+
+func TestInferSchema_ref_option(t *testing.T) {
+	got, err := InferSchema("avro", Subscription{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Subscription","type":"record","fields":[`+
+		`{"name":"owner","type":"com.acme.crm.Client"},`+
+		`{"name":"billing","type":["null","com.acme.Address"],"default":null},`+
+		`{"name":"tier","type":"Tier","default":"GOLD"}]}`, got)
+
+	tests := []struct {
+		field reflect.StructField
+		err   string
+	}{
+		{
+			reflect.StructField{Name: "Owner", Type: reflect.TypeOf(""), Tag: `avro:"owner,ref=com..Client"`},
+			`Owner: invalid ref "com..Client" of owner, must be the full name of a named type`,
+		},
+		{
+			reflect.StructField{Name: "Owner", Type: reflect.TypeOf(""), Tag: `avro:"owner,ref=string"`},
+			`Owner: invalid ref "string" of owner, must be the full name of a named type`,
+		},
+		{
+			reflect.StructField{Name: "Owner", Type: reflect.TypeOf(""), Tag: `avro:"owner,ref=Client,type=string"`},
+			"Owner: ref of owner is exclusive with schema and type",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := (&inferrer{}).inferField(tt.field, "", "", 0)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+// countdownContext is canceled once Err has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (ctx *countdownContext) Err() error {
+	if ctx.n--; ctx.n < 0 {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+// syntheticType returns a struct of the given width, nested depth times, its fields being named after their path.
+func syntheticType(prefix string, width, depth int) reflect.Type {
+	fields := make([]reflect.StructField, width)
+	for i := range fields {
+		name := fmt.Sprintf("%sF%d", prefix, i)
+
+		fields[i] = reflect.StructField{Name: name, Type: reflect.TypeOf(int64(0))}
+		if depth > 0 {
+			fields[i].Type = syntheticType(name, width, depth-1)
+		}
+	}
+
+	return reflect.StructOf(fields)
+}
+
+func TestInferSchemaContext(t *testing.T) {
+	v := reflect.New(syntheticType("", 4, 4)).Elem().Interface()
+
+	got, err := InferSchemaContext(context.Background(), v, WithRecordName("Synthetic"), WithoutCache())
+	assert.NoError(t, err)
+	assert.Contains(t, got, `{"name":"F3F3F3F3F3","type":"long"}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = InferSchemaContext(ctx, v, WithRecordName("Synthetic"), WithoutCache())
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = InferSchemaContext(&countdownContext{Context: context.Background(), n: 100}, v,
+		WithRecordName("Synthetic"), WithoutCache(), WithCollectErrors())
+	if assert.Error(t, err) {
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Regexp(t, `^infer schema: ([F\d]+\.)+[F\d]+: context canceled$`, err.Error())
+	}
+
+	// a cached schema isn't returned once the context is done, however early
+	_, err = InferSchemaContext(context.Background(), v, WithRecordName("Synthetic"))
+	assert.NoError(t, err)
+
+	_, err = InferSchemaContext(ctx, v, WithRecordName("Synthetic"))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = InferSchemaContext(&countdownContext{Context: context.Background()}, v, WithRecordName("Synthetic"))
+	assert.EqualError(t, err, "infer schema: context canceled")
+}
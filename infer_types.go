@@ -0,0 +1,255 @@
+package avro
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// typeRegistry maps Go types to the avro schemas registered with RegisterType and RegisterTypeName.
+var typeRegistry = struct {
+	sync.RWMutex
+	schemas map[reflect.Type]func() TypedSchema
+	// names holds the mappings by package path and type name
+	names map[string]func() TypedSchema
+	// optionals holds the generic optional wrappers by package path and type name
+	optionals map[string]optionalFields
+	// unions holds the implementations of the interfaces registered with RegisterUnion
+	unions map[reflect.Type][]reflect.Type
+}{
+	schemas:   make(map[reflect.Type]func() TypedSchema),
+	names:     make(map[string]func() TypedSchema),
+	optionals: make(map[string]optionalFields),
+	unions:    make(map[reflect.Type][]reflect.Type),
+}
+
+// optionalFields are the names of the fields of a generic optional wrapper registered with RegisterOptional.
+type optionalFields struct {
+	value, present string
+}
+
+// RegisterType maps the Go type t to the avro schema returned by fn.
+// The mapping is consulted before the structural inference, so it wins over the default handling
+// of structs, slices, maps and primitives. Named schemas (records, enums and fixed) are defined once per schema
+// and referenced by their full name afterwards.
+//
+// Registering a type again replaces its mapping and registering a nil fn removes it.
+// Either way the cache of the inferred schemas is cleared.
+func RegisterType(t reflect.Type, fn func() TypedSchema) {
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+
+	defer ClearSchemaCache()
+
+	if fn == nil {
+		delete(typeRegistry.schemas, t)
+		return
+	}
+
+	typeRegistry.schemas[t] = fn
+}
+
+// RegisterTypeName maps the Go types named name, qualified by their package path such as github.com/google/uuid.UUID,
+// to the avro schema returned by fn. It maps the types of packages this one doesn't depend on.
+// The mappings registered with RegisterType take precedence.
+//
+// Registering a name again replaces its mapping and registering a nil fn removes it.
+func RegisterTypeName(name string, fn func() TypedSchema) {
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+
+	defer ClearSchemaCache()
+
+	if fn == nil {
+		delete(typeRegistry.names, name)
+		return
+	}
+
+	typeRegistry.names[name] = fn
+}
+
+// RegisterOptional maps the instantiations of the generic struct named name, qualified by its package path,
+// to nullable unions of the type of their field named value, the bool field named present telling if it is set.
+// For instance, given
+//
+//	type Optional[T any] struct {
+//		Value T
+//		Set   bool
+//	}
+//
+// RegisterOptional("example.com/opt.Optional", "Value", "Set") infers Optional[int] as ["null","int"],
+// Marshal writes null when Set is false and Unmarshal sets Set when the value isn't null.
+// Both fields must be exported.
+//
+// Registering a name again replaces its mapping and registering an empty value field removes it.
+func RegisterOptional(name, value, present string) {
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+
+	defer ClearSchemaCache()
+
+	if value == "" {
+		delete(typeRegistry.optionals, name)
+		return
+	}
+
+	typeRegistry.optionals[name] = optionalFields{value: value, present: present}
+}
+
+// RegisterUnion maps the interface type iface to the union of the records of its implementations impls, given as
+// values such as Circle{} or (*Square)(nil) for the implementations with pointer receivers. A pointer to iface is
+// a nullable union. Marshal writes the branch of the dynamic type of the value, and Unmarshal sets the interface
+// to a new value of the implementation named after the branch it reads, a pointer if it was registered as one.
+//
+// Registering an interface again replaces its implementations and registering none removes them.
+// It panics if iface isn't an interface or an implementation isn't a struct, or a pointer to one, implementing it.
+func RegisterUnion(iface reflect.Type, impls ...interface{}) {
+	if iface == nil || iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("avro: RegisterUnion of %v, which isn't an interface", iface))
+	}
+
+	types := make([]reflect.Type, len(impls))
+	for i, impl := range impls {
+		t := reflect.TypeOf(impl)
+		if t == nil || !t.Implements(iface) || (t.Kind() != reflect.Struct && (t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct)) {
+			panic(fmt.Sprintf("avro: RegisterUnion of %s with %v, which isn't a struct implementing it", iface, t))
+		}
+
+		types[i] = t
+	}
+
+	typeRegistry.Lock()
+	defer typeRegistry.Unlock()
+
+	defer ClearSchemaCache()
+
+	if len(types) == 0 {
+		delete(typeRegistry.unions, iface)
+		return
+	}
+
+	typeRegistry.unions[iface] = types
+}
+
+// registeredUnion returns the implementations registered for the interface t, if any.
+func registeredUnion(t reflect.Type) ([]reflect.Type, bool) {
+	if t.Kind() != reflect.Interface {
+		return nil, false
+	}
+
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+
+	impls, ok := typeRegistry.unions[t]
+
+	return impls, ok
+}
+
+// registeredOptional returns the fields of the optional wrapper t is an instantiation of, if any.
+func registeredOptional(t reflect.Type) (optionalFields, bool) {
+	// the instantiations are named after the generic type and their type arguments, as in Optional[int]
+	i := strings.IndexByte(t.Name(), '[')
+	if i < 0 || t.Kind() != reflect.Struct {
+		return optionalFields{}, false
+	}
+
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+
+	fields, ok := typeRegistry.optionals[t.PkgPath()+"."+t.Name()[:i]]
+
+	return fields, ok
+}
+
+// check checks that the optional wrapper t has the exported fields value and present, a bool.
+func (fields optionalFields) check(t reflect.Type) error {
+	value, ok := t.FieldByName(fields.value)
+	if !ok || value.PkgPath != "" {
+		return fmt.Errorf("optional %s has no exported field %s", t, fields.value)
+	}
+
+	present, ok := t.FieldByName(fields.present)
+	if !ok || present.PkgPath != "" || present.Type.Kind() != reflect.Bool {
+		return fmt.Errorf("optional %s has no exported bool field %s", t, fields.present)
+	}
+
+	return nil
+}
+
+// registeredType returns the mapping registered for t, if any.
+func registeredType(t reflect.Type) (func() TypedSchema, bool) {
+	typeRegistry.RLock()
+	defer typeRegistry.RUnlock()
+
+	if fn, ok := typeRegistry.schemas[t]; ok {
+		return fn, true
+	}
+
+	if t.Name() == "" {
+		return nil, false
+	}
+
+	fn, ok := typeRegistry.names[t.PkgPath()+"."+t.Name()]
+
+	return fn, ok
+}
+
+// nullable returns the mapping of a type to a union of null and the given schema.
+func nullable(schema interface{}) func() TypedSchema {
+	return func() TypedSchema {
+		return TypedSchema{Type: []interface{}{"null", schema}}
+	}
+}
+
+// the built-in mappings of the standard library types
+func init() {
+	// the sql.Null* types are nullable unions of their underlying type
+	RegisterType(reflect.TypeOf(sql.NullString{}), nullable("string"))
+	RegisterType(reflect.TypeOf(sql.NullBool{}), nullable("boolean"))
+	RegisterType(reflect.TypeOf(sql.NullByte{}), nullable("int"))
+	RegisterType(reflect.TypeOf(sql.NullInt16{}), nullable("int"))
+	RegisterType(reflect.TypeOf(sql.NullInt32{}), nullable("int"))
+	RegisterType(reflect.TypeOf(sql.NullInt64{}), nullable("long"))
+	RegisterType(reflect.TypeOf(sql.NullFloat64{}), nullable("double"))
+	RegisterType(reflect.TypeOf(sql.NullTime{}), nullable(TypedSchema{Type: "long", LogicalType: "timestamp-millis"}))
+
+	// net.IP is bytes, either 4 or 16 of them, and time.Duration a plain long counting nanoseconds,
+	// as the avro duration, a fixed of months, days and milliseconds, can't hold it
+	RegisterType(reflect.TypeOf(net.IP{}), func() TypedSchema {
+		return TypedSchema{Type: "bytes"}
+	})
+	RegisterType(reflect.TypeOf(time.Duration(0)), func() TypedSchema {
+		return TypedSchema{Type: "long"}
+	})
+
+	// a big.Float is a double, rounded to its precision
+	RegisterType(reflect.TypeOf(big.Float{}), func() TypedSchema {
+		return TypedSchema{Type: "double"}
+	})
+
+	// the civil dates and times of day of the Google Cloud libraries are written as date and time-millis,
+	// through their text form
+	RegisterTypeName("cloud.google.com/go/civil.Date", func() TypedSchema {
+		return TypedSchema{Type: "int", LogicalType: "date"}
+	})
+	RegisterTypeName("cloud.google.com/go/civil.Time", func() TypedSchema {
+		return TypedSchema{Type: "int", LogicalType: "time-millis"}
+	})
+
+	// the protobuf timestamps are written as timestamp-micros, through their AsTime method and Seconds and Nanos fields
+	RegisterTypeName("google.golang.org/protobuf/types/known/timestamppb.Timestamp", func() TypedSchema {
+		return TypedSchema{Type: "long", LogicalType: "timestamp-micros"}
+	})
+
+	// the common UUID types are written as strings, through their driver.Valuer and sql.Scanner implementations
+	uuid := TypedSchema{Type: "string", LogicalType: "uuid"}
+	for _, pkg := range []string{"github.com/google/uuid", "github.com/gofrs/uuid", "github.com/gofrs/uuid/v5"} {
+		RegisterTypeName(pkg+".UUID", func() TypedSchema { return uuid })
+		RegisterTypeName(pkg+".NullUUID", nullable(uuid))
+	}
+}
@@ -0,0 +1,302 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// InferSchemaFromJSON infers the avro record schema named recordName from a representative JSON document,
+// for dynamic data without a Go type to infer it from.
+//
+// The strings, booleans and objects are inferred as strings, booleans and nested records named after their field,
+// the integers as longs and the other numbers as doubles. The items of an array are merged into a single type,
+// the fields missing from some of the objects and the null values making it nullable.
+// The fields are sorted by name, the JSON objects being unordered.
+func InferSchemaFromJSON(data []byte, recordName string, opts ...Option) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("decode JSON: %w", err)
+	}
+
+	if _, ok := v.(map[string]interface{}); !ok {
+		return "", errors.New("JSON sample must be an object")
+	}
+
+	sample, err := sampleJSON(v)
+	if err != nil {
+		return "", fmt.Errorf("infer schema: %w", err)
+	}
+
+	o := newInferOptions(opts)
+	in := jsonInferrer{inferrer: inferrer{inferOptions: o}, named: make(map[string]bool)}
+
+	s, err := in.record(sample, recordName, "")
+	if err != nil {
+		return "", fmt.Errorf("infer schema: %w", err)
+	}
+
+	for _, alias := range o.aliases {
+		if !isFullName(alias) {
+			return "", fmt.Errorf("invalid alias %q", alias)
+		}
+	}
+
+	s.Doc = o.doc
+	s.Aliases = o.aliases
+
+	return marshalSchema(s, o.indent)
+}
+
+// jsonSample is the type of a JSON value, merged from all the values found at the same place of the document.
+type jsonSample struct {
+	// typ is the avro type of the values, or empty if they are all null
+	typ string
+	// nullable tells if some of the values are null or missing
+	nullable bool
+	// items is the type of the items of an array, nil if all the arrays are empty
+	items *jsonSample
+	// fields are the types of the fields of an object
+	fields map[string]*jsonSample
+}
+
+// sampleJSON returns the type of the decoded JSON value v.
+func sampleJSON(v interface{}) (*jsonSample, error) {
+	switch v := v.(type) {
+	case nil:
+		return &jsonSample{nullable: true}, nil
+
+	case bool:
+		return &jsonSample{typ: "boolean"}, nil
+
+	case string:
+		return &jsonSample{typ: "string"}, nil
+
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return &jsonSample{typ: "long"}, nil
+		}
+
+		return &jsonSample{typ: "double"}, nil
+
+	case []interface{}:
+		s := &jsonSample{typ: "array"}
+
+		for _, item := range v {
+			sample, err := sampleJSON(item)
+			if err != nil {
+				return nil, prependPath("[]", err)
+			}
+
+			if s.items, err = mergeJSON(s.items, sample); err != nil {
+				return nil, prependPath("[]", err)
+			}
+		}
+
+		return s, nil
+
+	case map[string]interface{}:
+		s := &jsonSample{typ: "record", fields: make(map[string]*jsonSample, len(v))}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+
+		// the keys are sorted for the errors to be reported in a stable order
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			sample, err := sampleJSON(v[key])
+			if err != nil {
+				return nil, prependPath(key, err)
+			}
+
+			s.fields[key] = sample
+		}
+
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("unexpected JSON value %v of type %T", v, v)
+}
+
+// mergeJSON returns the type of the values of both the types a and b, a being nil for no values.
+func mergeJSON(a, b *jsonSample) (*jsonSample, error) {
+	if a == nil {
+		return b, nil
+	}
+
+	merged := &jsonSample{typ: a.typ, nullable: a.nullable || b.nullable, items: a.items, fields: a.fields}
+
+	switch {
+	case b.typ == "" || a.typ == b.typ && a.typ != "array" && a.typ != "record":
+
+	case a.typ == "":
+		merged.typ, merged.items, merged.fields = b.typ, b.items, b.fields
+
+	case a.typ == "long" && b.typ == "double", a.typ == "double" && b.typ == "long":
+		merged.typ = "double"
+
+	case a.typ == "array" && b.typ == "array":
+		items, err := mergeJSON(a.items, b.items)
+		if err != nil {
+			return nil, prependPath("[]", err)
+		}
+
+		merged.items = items
+
+	case a.typ == "record" && b.typ == "record":
+		merged.fields = make(map[string]*jsonSample, len(a.fields))
+
+		for _, key := range fieldKeys(a.fields, b.fields) {
+			fa, fb := a.fields[key], b.fields[key]
+
+			switch {
+			case fa == nil:
+				merged.fields[key] = &jsonSample{typ: fb.typ, nullable: true, items: fb.items, fields: fb.fields}
+			case fb == nil:
+				merged.fields[key] = &jsonSample{typ: fa.typ, nullable: true, items: fa.items, fields: fa.fields}
+			default:
+				f, err := mergeJSON(fa, fb)
+				if err != nil {
+					return nil, prependPath(key, err)
+				}
+
+				merged.fields[key] = f
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("conflicting types %s and %s", a.typ, b.typ)
+	}
+
+	return merged, nil
+}
+
+// fieldKeys returns the sorted keys of all the fields.
+func fieldKeys(fields ...map[string]*jsonSample) []string {
+	seen := make(map[string]bool)
+
+	var keys []string
+	for _, m := range fields {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// jsonInferrer turns the types of a JSON document into a schema.
+type jsonInferrer struct {
+	inferrer
+	// named holds the names of the records already defined
+	named map[string]bool
+}
+
+// record returns the record named name of the object type s, parent being the name of the enclosing record.
+func (in *jsonInferrer) record(s *jsonSample, name, parent string) (TypedSchema, error) {
+	if in.named[name] {
+		// the objects of fields of the same name are told apart by the name of their record
+		name = parent + name
+	}
+
+	r := TypedSchema{Type: "record", Name: name, Namespace: in.namespace}
+	if err := checkNames(r); err != nil {
+		return r, err
+	}
+
+	if in.named[name] {
+		return r, fmt.Errorf("record %s defined twice", name)
+	}
+
+	in.named[name] = true
+
+	if len(s.fields) == 0 {
+		return r, errors.New("the fields of an empty object can't be inferred")
+	}
+
+	for _, key := range fieldKeys(s.fields) {
+		if !avroNameRegexp.MatchString(key) {
+			return r, prependPath(key, fmt.Errorf("invalid field name %q, must match %s", key, avroNameRegexp))
+		}
+
+		f, err := in.field(s.fields[key], key, name)
+		if err != nil {
+			return r, prependPath(key, err)
+		}
+
+		r.Fields = append(r.Fields, f)
+	}
+
+	return r, nil
+}
+
+// field returns the record field key of the type s, within the record named parent.
+func (in *jsonInferrer) field(s *jsonSample, key, parent string) (TypedSchema, error) {
+	typ, err := in.schema(s, exportedName(key), parent)
+	if err != nil {
+		return TypedSchema{}, err
+	}
+
+	f := TypedSchema{Name: key, Type: typ.schema()}
+
+	if s.nullable && s.typ != "" {
+		f.Type = in.nullable(typ)
+	}
+
+	if s.typ == "" || isNullFirst(f) {
+		f.Default = Null{}
+	}
+
+	return f, nil
+}
+
+// schema returns the schema of the type s, name being the name of its record if it is an object.
+func (in *jsonInferrer) schema(s *jsonSample, name, parent string) (TypedSchema, error) {
+	switch s.typ {
+	case "":
+		return TypedSchema{Type: "null"}, nil
+
+	case "record":
+		return in.record(s, name, parent)
+
+	case "array":
+		if s.items == nil {
+			if in.emptyArrayItems == "" {
+				return TypedSchema{}, errors.New("the item type of an empty array can't be inferred, set it with WithEmptyArrayItems")
+			}
+
+			items, err := typeNames(splitUnion(in.emptyArrayItems))
+			if err != nil {
+				return TypedSchema{}, err
+			}
+
+			return TypedSchema{Type: "array", Items: items}, nil
+		}
+
+		items, err := in.schema(s.items, name, parent)
+		if err != nil {
+			return TypedSchema{}, prependPath("[]", err)
+		}
+
+		if s.items.nullable && s.items.typ != "" {
+			return TypedSchema{Type: "array", Items: in.nullable(items)}, nil
+		}
+
+		return TypedSchema{Type: "array", Items: items.schema()}, nil
+	}
+
+	return TypedSchema{Type: s.typ}, nil
+}
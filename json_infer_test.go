@@ -0,0 +1,110 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferSchemaFromJSON(t *testing.T) {
+	sample := `{
+	  "id": 42,
+	  "name": "Bob",
+	  "score": 1.5,
+	  "active": true,
+	  "nickname": null,
+	  "address": {"city": "Paris", "zip": "75001"},
+	  "tags": ["a", "b"],
+	  "orders": [{"id": 1, "total": 10}, {"id": 2, "total": 12.5, "coupon": "WELCOME"}]
+	}`
+
+	schema, err := InferSchemaFromJSON([]byte(sample), "User", WithNamespace("com.example"))
+	assert.NoError(t, err)
+
+	expected := `{"type":"record","name":"User","namespace":"com.example","fields":[` +
+		`{"name":"active","type":"boolean"},` +
+		`{"name":"address","type":{"type":"record","name":"Address","namespace":"com.example","fields":[{"name":"city","type":"string"},{"name":"zip","type":"string"}]}},` +
+		`{"name":"id","type":"long"},` +
+		`{"name":"name","type":"string"},` +
+		`{"name":"nickname","type":"null","default":null},` +
+		`{"name":"orders","type":{"type":"array","items":{"type":"record","name":"Orders","namespace":"com.example","fields":[{"name":"coupon","type":["null","string"],"default":null},{"name":"id","type":"long"},{"name":"total","type":"double"}]}}},` +
+		`{"name":"score","type":"double"},` +
+		`{"name":"tags","type":{"type":"array","items":"string"}}]}`
+	assert.JSONEq(t, expected, schema)
+
+	_, err = goavro.NewCodec(schema)
+	assert.NoError(t, err)
+}
+
+func TestInferSchemaFromJSON_nullable(t *testing.T) {
+	schema, err := InferSchemaFromJSON([]byte(`{"values": [1, null, 2], "rows": [{"a": null}, {"a": "x"}]}`), "Sample")
+	assert.NoError(t, err)
+
+	expected := `{"type":"record","name":"Sample","fields":[` +
+		`{"name":"rows","type":{"type":"array","items":{"type":"record","name":"Rows","fields":[{"name":"a","type":["null","string"],"default":null}]}}},` +
+		`{"name":"values","type":{"type":"array","items":["null","long"]}}]}`
+	assert.JSONEq(t, expected, schema)
+}
+
+func TestInferSchemaFromJSON_record_names(t *testing.T) {
+	schema, err := InferSchemaFromJSON([]byte(`{"meta": {"a": 1}, "child": {"meta": {"b": true}}}`), "Root")
+	assert.NoError(t, err)
+
+	expected := `{"type":"record","name":"Root","fields":[` +
+		`{"name":"child","type":{"type":"record","name":"Child","fields":[{"name":"meta","type":{"type":"record","name":"Meta","fields":[{"name":"b","type":"boolean"}]}}]}},` +
+		`{"name":"meta","type":{"type":"record","name":"RootMeta","fields":[{"name":"a","type":"long"}]}}]}`
+	assert.JSONEq(t, expected, schema)
+}
+
+func TestInferSchemaFromJSON_empty_array(t *testing.T) {
+	_, err := InferSchemaFromJSON([]byte(`{"user": {"tags": []}}`), "Sample")
+	assert.EqualError(t, err, "infer schema: user.tags: the item type of an empty array can't be inferred, set it with WithEmptyArrayItems")
+
+	schema, err := InferSchemaFromJSON([]byte(`{"tags": [], "lists": [[], ["a"]]}`), "Sample", WithEmptyArrayItems("string"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"record","name":"Sample","fields":[`+
+		`{"name":"lists","type":{"type":"array","items":{"type":"array","items":"string"}}},`+
+		`{"name":"tags","type":{"type":"array","items":"string"}}]}`, schema)
+}
+
+func TestInferSchemaFromJSON_errors(t *testing.T) {
+	for _, tc := range []struct {
+		sample   string
+		name     string
+		expected string
+	}{
+		{`[1]`, "Sample", "JSON sample must be an object"},
+		{`{"a":`, "Sample", "decode JSON: unexpected EOF"},
+		{`{"a": 1}`, "my-sample", `infer schema: invalid record name "my-sample", must match ^[A-Za-z_][A-Za-z0-9_]*$`},
+		{`{"first-name": "Bob"}`, "Sample", `infer schema: first-name: invalid field name "first-name", must match ^[A-Za-z_][A-Za-z0-9_]*$`},
+		{`{"items": [1, "a"]}`, "Sample", "infer schema: items[]: conflicting types long and string"},
+		{`{"rows": [{"a": 1}, {"a": {"b": 1}}]}`, "Sample", "infer schema: rows[].a: conflicting types long and record"},
+		{`{"a": {}}`, "Sample", "infer schema: a: the fields of an empty object can't be inferred"},
+	} {
+		_, err := InferSchemaFromJSON([]byte(tc.sample), tc.name)
+		assert.EqualError(t, err, tc.expected, tc.sample)
+	}
+}
+
+func TestInferSchemaFromJSON_stable_output(t *testing.T) {
+	sample := []byte(`{"z": 1, "a": {"y": [{"k": "v"}, {"j": 2}], "x": null}, "m": [1.5, 2]}`)
+
+	got, err := InferSchemaFromJSON(sample, "Sample")
+	assert.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		again, err := InferSchemaFromJSON(sample, "Sample")
+		assert.NoError(t, err)
+
+		if !assert.Equal(t, got, again, "run %d", i) {
+			return
+		}
+	}
+
+	// the error of the first field in key order is reported
+	for i := 0; i < 20; i++ {
+		_, err := InferSchemaFromJSON([]byte(`{"b": [1, "x"], "a": [true, 1]}`), "Sample")
+		assert.EqualError(t, err, "infer schema: a[]: conflicting types boolean and long")
+	}
+}
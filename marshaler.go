@@ -0,0 +1,180 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroMarshaler is implemented by the types which encode themselves to avro binary, Marshal calling MarshalAvro
+// instead of walking the value. The schema holds the type the value is written as, the named types it references
+// being defined within it. The data must hold a single value of that type.
+//
+// The schema of such a type can't be inferred from its fields but can be registered with RegisterType.
+type AvroMarshaler interface {
+	MarshalAvro(schema TypedSchema) ([]byte, error)
+}
+
+// AvroUnmarshaler is implemented by the types which decode themselves from avro binary, Unmarshal calling
+// UnmarshalAvro with the schema and the data of the value instead of assigning its fields.
+type AvroUnmarshaler interface {
+	UnmarshalAvro(schema TypedSchema, data []byte) error
+}
+
+var avroUnmarshalerType = reflect.TypeOf((*AvroUnmarshaler)(nil)).Elem()
+
+// avroMarshaler returns the AvroMarshaler implemented by v or the value it points to, if any.
+func avroMarshaler(v reflect.Value) (AvroMarshaler, bool) {
+	for v.IsValid() {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil, false
+		}
+
+		if m, ok := asInterface(v).(AvroMarshaler); ok {
+			return m, true
+		}
+
+		if v.CanAddr() {
+			if m, ok := asInterface(v.Addr()).(AvroMarshaler); ok {
+				return m, true
+			}
+		}
+
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			break
+		}
+
+		v = v.Elem()
+	}
+
+	return nil, false
+}
+
+// asInterface returns the value held by v, or nil if it can't be accessed.
+func asInterface(v reflect.Value) interface{} {
+	if !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// marshalAvro returns the native form of the data written by MarshalAvro for the parsed schema typ.
+func (val *validator) marshalAvro(m AvroMarshaler, typ interface{}, path string) (interface{}, error) {
+	s, codec, err := val.standaloneCodec(typ)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pathOrRoot(path), err)
+	}
+
+	b, err := m.MarshalAvro(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: MarshalAvro: %w", pathOrRoot(path), err)
+	}
+
+	native, rest, err := codec.NativeFromBinary(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: MarshalAvro wrote invalid data: %w", pathOrRoot(path), err)
+	}
+
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%s: MarshalAvro wrote %d bytes past the value", pathOrRoot(path), len(rest))
+	}
+
+	return native, nil
+}
+
+// unmarshalAvro encodes back the native value decoded for the parsed schema typ and passes it to UnmarshalAvro.
+func (val *validator) unmarshalAvro(u AvroUnmarshaler, typ interface{}, native interface{}, path string) error {
+	s, codec, err := val.standaloneCodec(typ)
+	if err != nil {
+		return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+	}
+
+	b, err := codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+	}
+
+	if err := u.UnmarshalAvro(s, b); err != nil {
+		return fmt.Errorf("%s: UnmarshalAvro: %w", pathOrRoot(path), err)
+	}
+
+	return nil
+}
+
+// standaloneCodec returns the parsed schema typ with the named types it references defined within it,
+// along with its codec.
+func (val *validator) standaloneCodec(typ interface{}) (TypedSchema, *goavro.Codec, error) {
+	expanded := val.standalone(typ, make(map[string]bool))
+
+	s, ok := expanded.(TypedSchema)
+	if !ok {
+		s = TypedSchema{Type: expanded}
+	}
+
+	schema, err := marshalSchema(s, "")
+	if err != nil {
+		return s, nil, err
+	}
+
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return s, nil, fmt.Errorf("parse schema: %w", err)
+	}
+
+	return s, codec, nil
+}
+
+// standalone returns the parsed schema typ with the references to the named types not in defined replaced by
+// their definition.
+func (val *validator) standalone(typ interface{}, defined map[string]bool) interface{} {
+	switch typ := typ.(type) {
+	case string:
+		named, ok := val.named[typ]
+		if !ok || defined[typ] {
+			return typ
+		}
+
+		return val.standalone(named, defined)
+
+	case []interface{}:
+		union := make([]interface{}, len(typ))
+		for i, branch := range typ {
+			union[i] = val.standalone(branch, defined)
+		}
+
+		return union
+
+	case TypedSchema:
+		if isNamed(typ) {
+			defined[AddNamespace(typ.Namespace, typ.Name)] = true
+		}
+
+		switch typ.Type {
+		case "record", "error":
+			fields := make([]TypedSchema, len(typ.Fields))
+			for i, f := range typ.Fields {
+				f.Type = val.standalone(f.Type, defined)
+				fields[i] = f
+			}
+
+			typ.Fields = fields
+
+		case "array":
+			typ.Items = val.standalone(typ.Items, defined)
+
+		case "map":
+			typ.Values = val.standalone(typ.Values, defined)
+
+		case "enum", "fixed":
+
+		default:
+			typ.Type = val.standalone(typ.Type, defined)
+		}
+
+		return typ
+	}
+
+	return typ
+}
@@ -0,0 +1,149 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Version writes itself as a string such as 1.2.3.
+type Version struct {
+	major, minor, patch int
+}
+
+func (v Version) MarshalAvro(schema TypedSchema) ([]byte, error) {
+	codec, err := schemaCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.BinaryFromNative(nil, fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch))
+}
+
+func (v *Version) UnmarshalAvro(schema TypedSchema, data []byte) error {
+	codec, err := schemaCodec(schema)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := codec.NativeFromBinary(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Sscanf(native.(string), "%d.%d.%d", &v.major, &v.minor, &v.patch)
+
+	return err
+}
+
+// Origin writes the Location it references by name.
+type Origin struct {
+	street string
+}
+
+func (o Origin) MarshalAvro(schema TypedSchema) ([]byte, error) {
+	if schema.Name != "Location" {
+		return nil, errors.New("origin must be a Location")
+	}
+
+	codec, err := schemaCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.BinaryFromNative(nil, map[string]interface{}{"street": o.street})
+}
+
+// Truncated writes a long it doesn't complete.
+type Truncated struct{}
+
+func (Truncated) MarshalAvro(TypedSchema) ([]byte, error) {
+	return []byte{0x80}, nil
+}
+
+func schemaCodec(schema TypedSchema) (*goavro.Codec, error) {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return goavro.NewCodec(string(b))
+}
+
+type Release struct {
+	Name     string   `avro:"name"`
+	Version  Version  `avro:"version"`
+	Previous *Version `avro:"previous"`
+	Home     Location `avro:"home"`
+	Origin   Origin   `avro:"origin"`
+}
+
+func TestMarshal_avro_marshalers(t *testing.T) {
+	schema := `{"type":"record","name":"Release","fields":[
+		{"name":"name","type":"string"},
+		{"name":"version","type":"string"},
+		{"name":"previous","type":["null","string"]},
+		{"name":"home","type":{"type":"record","name":"Location","fields":[{"name":"street","type":"string"}]}},
+		{"name":"origin","type":"Location"}
+	]}`
+
+	release := Release{
+		Name:     "avrocado",
+		Version:  Version{1, 2, 3},
+		Previous: &Version{1, 1, 0},
+		Home:     Location{Street: "Main Street"},
+		Origin:   Origin{street: "Rue de Rivoli"},
+	}
+
+	b, err := Marshal(schema, release)
+	assert.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "avrocado",
+		"version":  "1.2.3",
+		"previous": map[string]interface{}{"string": "1.1.0"},
+		"home":     map[string]interface{}{"street": "Main Street"},
+		"origin":   map[string]interface{}{"street": "Rue de Rivoli"},
+	}, native)
+
+	var decoded struct {
+		Version  Version  `avro:"version"`
+		Previous *Version `avro:"previous"`
+	}
+	assert.NoError(t, Unmarshal(schema, b, &decoded))
+	assert.Equal(t, Version{1, 2, 3}, decoded.Version)
+	assert.Equal(t, &Version{1, 1, 0}, decoded.Previous)
+
+	release.Previous = nil
+	b, err = Marshal(schema, release)
+	assert.NoError(t, err)
+	assert.NoError(t, Unmarshal(schema, b, &decoded))
+	assert.Nil(t, decoded.Previous)
+}
+
+func TestMarshal_avro_marshaler_errors(t *testing.T) {
+	_, err := Marshal(`{"type":"record","name":"Release","fields":[{"name":"origin","type":"string"}]}`,
+		struct {
+			Origin Origin `avro:"origin"`
+		}{})
+	assert.EqualError(t, err, "origin: MarshalAvro: origin must be a Location")
+
+	_, err = Marshal(`"long"`, Truncated{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "value: MarshalAvro wrote invalid data: ")
+	}
+
+	_, err = Marshal(`"int"`, Version{1, 0, 0})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "value: MarshalAvro: ")
+	}
+}
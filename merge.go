@@ -0,0 +1,204 @@
+package avro
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// InferSchemaMerge will infer the avro schema from a Go struct like InferSchema, carrying over the docs, defaults
+// and aliases of the records and fields of the base schema the inferred ones don't set. The fields are matched by
+// name or alias, those of the nested records as well, so that the types follow the struct while the hand-written
+// metadata is kept.
+//
+// A field of the base schema missing from the struct is an error, unless WithDropBaseFields is set.
+func InferSchemaMerge(base, fallbackTag string, v interface{}, opts ...Option) (string, error) {
+	if v == nil {
+		return "", errNilValue
+	}
+
+	b, p, err := parseSchema(base)
+	if err != nil {
+		return "", fmt.Errorf("parse base schema: %w", err)
+	}
+
+	o := newInferOptions(append([]Option{WithFallbackTag(fallbackTag)}, opts...))
+
+	s, err := inferTree(context.Background(), reflect.TypeOf(v), o)
+	if err != nil {
+		return "", err
+	}
+
+	m := merger{named: p.named, dropBaseFields: o.dropBaseFields}
+
+	merged, err := m.merge(s, b.schema(), "")
+	if err != nil {
+		return "", fmt.Errorf("merge base schema: %w", err)
+	}
+
+	// the merged type of a TypedSchema is a TypedSchema
+	schema, err := marshalSchema(merged.(TypedSchema), o.indent)
+	if err != nil {
+		return "", err
+	}
+
+	// a default of the base may not be a value of the inferred type
+	if _, err := goavro.NewCodec(schema); err != nil {
+		return "", fmt.Errorf("merge base schema: %w", err)
+	}
+
+	return schema, nil
+}
+
+// merger carries the metadata of a parsed base schema over to an inferred one.
+type merger struct {
+	named          map[string]TypedSchema
+	dropBaseFields bool
+}
+
+// merge returns the inferred type typ with the metadata of the parsed base type, path being the path of the field.
+func (m *merger) merge(typ, base interface{}, path string) (interface{}, error) {
+	switch typ := typ.(type) {
+	case []interface{}:
+		union := make([]interface{}, len(typ))
+		for i, branch := range typ {
+			var err error
+			if union[i], err = m.merge(branch, base, path); err != nil {
+				return nil, err
+			}
+		}
+
+		return union, nil
+
+	case TypedSchema:
+		switch typ.Type {
+		case "record", "error":
+			if record, ok := nestedRecord(base, m.named); ok {
+				return m.mergeRecord(typ, record, path)
+			}
+
+		case "array":
+			if s := resolveNode(base, m.named); s.Type == "array" {
+				items, err := m.merge(typ.Items, s.Items, path+"[]")
+				if err != nil {
+					return nil, err
+				}
+
+				typ.Items = items
+			}
+
+		case "map":
+			if s := resolveNode(base, m.named); s.Type == "map" {
+				values, err := m.merge(typ.Values, s.Values, path+"[]")
+				if err != nil {
+					return nil, err
+				}
+
+				typ.Values = values
+			}
+
+		default:
+			if branches, ok := typ.Type.([]interface{}); ok {
+				union, err := m.merge(branches, base, path)
+				if err != nil {
+					return nil, err
+				}
+
+				typ.Type = union
+			}
+		}
+
+		return typ, nil
+	}
+
+	return typ, nil
+}
+
+// mergeRecord returns the inferred record s with the metadata of the base record and of its fields.
+func (m *merger) mergeRecord(s, base TypedSchema, path string) (TypedSchema, error) {
+	if s.Doc == "" {
+		s.Doc = base.Doc
+	}
+
+	if s.Aliases == nil {
+		s.Aliases = base.Aliases
+	}
+
+	matched := make(map[string]bool, len(base.Fields))
+	fields := make([]TypedSchema, len(s.Fields))
+
+	for i, f := range s.Fields {
+		bf, ok := baseField(f, base)
+		if ok {
+			matched[bf.Name] = true
+
+			if f.Doc == "" {
+				f.Doc = bf.Doc
+			}
+
+			if f.Aliases == nil {
+				f.Aliases = fieldAliases(f.Name, bf)
+			}
+
+			if f.Default == nil {
+				f.Default = bf.Default
+			}
+
+			typ, err := m.merge(f.Type, bf.Type, joinPath(path, f.Name))
+			if err != nil {
+				return s, err
+			}
+
+			f.Type = typ
+		}
+
+		fields[i] = f
+	}
+
+	s.Fields = fields
+
+	if m.dropBaseFields {
+		return s, nil
+	}
+
+	for _, bf := range base.Fields {
+		if !matched[bf.Name] {
+			return s, fmt.Errorf("field %s of the base schema is missing from %s, drop it with WithDropBaseFields",
+				joinPath(path, bf.Name), typeName(s))
+		}
+	}
+
+	return s, nil
+}
+
+// baseField returns the field of the base record matching the inferred field f by name or alias.
+func baseField(f, base TypedSchema) (TypedSchema, bool) {
+	if bf, ok := writerField(f, base); ok {
+		return bf, true
+	}
+
+	for _, bf := range base.Fields {
+		for _, alias := range bf.Aliases {
+			if alias == f.Name {
+				return bf, true
+			}
+		}
+	}
+
+	return TypedSchema{}, false
+}
+
+// fieldAliases returns the name and aliases of the base field bf other than name, so that a renamed field
+// still reads the data written with the base schema.
+func fieldAliases(name string, bf TypedSchema) []string {
+	var aliases []string
+	for _, alias := range append([]string{bf.Name}, bf.Aliases...) {
+		if alias != name {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
@@ -0,0 +1,66 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Postal struct {
+	Zip  string `avro:"zip"`
+	City string `avro:"city"`
+}
+
+type Newsletter struct {
+	Email     string   `avro:"email"`
+	Frequency int64    `avro:"frequency"`
+	Postal    *Postal  `avro:"postal"`
+	Topics    []string `avro:"topics,doc=The topics subscribed to"`
+}
+
+func TestInferSchemaMerge(t *testing.T) {
+	base := `{"type":"record","name":"Newsletter","doc":"A subscription to the newsletter","fields":[
+		{"name":"mail","type":"string","doc":"The address the issues are sent to","aliases":["email"]},
+		{"name":"frequency","type":"int","doc":"Days between two issues","default":7},
+		{"name":"postal","type":["null",{"type":"record","name":"Postal","fields":[
+			{"name":"zip","type":"string","doc":"The postal code"}
+		]}],"default":null},
+		{"name":"topics","type":{"type":"array","items":"string"},"doc":"Replaced by the doc of the tag"}
+	]}`
+
+	got, err := InferSchemaMerge(base, "avro", Newsletter{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Newsletter","doc":"A subscription to the newsletter","type":"record","fields":[`+
+		`{"name":"email","doc":"The address the issues are sent to","aliases":["mail"],"type":"string"},`+
+		`{"name":"frequency","doc":"Days between two issues","type":"long","default":7},`+
+		`{"name":"postal","type":["null",{"name":"Postal","type":"record","fields":[`+
+		`{"name":"zip","doc":"The postal code","type":"string"},`+
+		`{"name":"city","type":"string"}]}],"default":null},`+
+		`{"name":"topics","doc":"The topics subscribed to","type":{"type":"array","items":"string"}}]}`, got)
+}
+
+func TestInferSchemaMerge_base_fields(t *testing.T) {
+	base := `{"type":"record","name":"Postal","fields":[
+		{"name":"zip","type":"string"},
+		{"name":"country","type":"string","doc":"Removed from the struct"}
+	]}`
+
+	_, err := InferSchemaMerge(base, "avro", Postal{})
+	assert.EqualError(t, err, "merge base schema: field country of the base schema is missing from Postal, drop it with WithDropBaseFields")
+
+	got, err := InferSchemaMerge(base, "avro", Postal{}, WithDropBaseFields())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Postal","type":"record","fields":[{"name":"zip","type":"string"},{"name":"city","type":"string"}]}`, got)
+
+	// the default of the base must be a value of the inferred type
+	_, err = InferSchemaMerge(`{"type":"record","name":"Postal","fields":[{"name":"zip","type":"int","default":75001}]}`,
+		"avro", Postal{}, WithDropBaseFields())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "merge base schema: ")
+	}
+
+	_, err = InferSchemaMerge(`{"type":"record"`, "avro", Postal{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "parse base schema: ")
+	}
+}
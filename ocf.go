@@ -0,0 +1,183 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// ocfBlockLength is the number of records the OCFWriter buffers before writing them as a block.
+const ocfBlockLength = 1000
+
+// errOCFClosed is the error returned when using a closed OCFWriter.
+var errOCFClosed = errors.New("ocf writer is closed")
+
+// ocfOptions configures an OCFWriter.
+type ocfOptions struct {
+	codec string
+}
+
+// OCFOption configures the OCFWriter returned by NewOCFWriter.
+type OCFOption func(*ocfOptions)
+
+// WithCodec sets the codec compressing the blocks of the file: null, the default, deflate or snappy.
+func WithCodec(name string) OCFOption {
+	return func(o *ocfOptions) {
+		o.codec = name
+	}
+}
+
+// OCFWriter writes Go values to an avro Object Container File, following the same conventions as Marshal.
+//
+// The records are buffered and written by blocks, so Close must be called to write the last block.
+type OCFWriter struct {
+	schema *compiledSchema
+	ocf    *goavro.OCFWriter
+	block  []interface{}
+	closed bool
+}
+
+// NewOCFWriter writes the header of an Object Container File of the given schema to w, along with
+// a random sync marker and the name of the codec, and returns an OCFWriter appending the records to it.
+// The schema can be the output of InferSchema.
+func NewOCFWriter(w io.Writer, schema string, opts ...OCFOption) (*OCFWriter, error) {
+	o := ocfOptions{codec: goavro.CompressionNullLabel}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch o.codec {
+	case goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel:
+	default:
+		return nil, fmt.Errorf("unsupported codec %q", o.codec)
+	}
+
+	c, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{W: w, Codec: c.codec, CompressionName: o.codec})
+	if err != nil {
+		return nil, fmt.Errorf("write header: %w", err)
+	}
+
+	return &OCFWriter{schema: c, ocf: ocf}, nil
+}
+
+// Append adds the Go value v to the file, writing a block once enough records are buffered.
+func (w *OCFWriter) Append(v interface{}) error {
+	if w.closed {
+		return errOCFClosed
+	}
+
+	native, err := w.schema.native(v)
+	if err != nil {
+		return err
+	}
+
+	w.block = append(w.block, native)
+	if len(w.block) >= ocfBlockLength {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// Flush writes the buffered records as a block.
+func (w *OCFWriter) Flush() error {
+	if w.closed {
+		return errOCFClosed
+	}
+
+	if len(w.block) == 0 {
+		return nil
+	}
+
+	if err := w.ocf.Append(w.block); err != nil {
+		return fmt.Errorf("write block: %w", err)
+	}
+
+	w.block = w.block[:0]
+
+	return nil
+}
+
+// Close writes the buffered records. It doesn't close the underlying writer.
+func (w *OCFWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	w.closed = true
+
+	return nil
+}
+
+// OCFReader reads Go values from an avro Object Container File, following the same conventions as Unmarshal.
+type OCFReader struct {
+	schema string
+	c      *compiledSchema
+	ocf    *goavro.OCFReader
+}
+
+// NewOCFReader reads the header of the Object Container File read from r and returns
+// an OCFReader decoding its records with the schema of the header.
+func NewOCFReader(r io.Reader) (*OCFReader, error) {
+	ocf, err := goavro.NewOCFReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	schema := string(ocf.MetaData()["avro.schema"])
+
+	c, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OCFReader{schema: schema, c: c, ocf: ocf}, nil
+}
+
+// Schema returns the schema of the file, as written in its header.
+func (r *OCFReader) Schema() string {
+	return r.schema
+}
+
+// CompressionName returns the name of the codec compressing the blocks of the file, as written in its header.
+func (r *OCFReader) CompressionName() string {
+	return r.ocf.CompressionName()
+}
+
+// MetaData returns the metadata of the header of the file, including the avro.schema and avro.codec entries.
+func (r *OCFReader) MetaData() map[string][]byte {
+	return r.ocf.MetaData()
+}
+
+// Read decodes the next record of the file into the Go value pointed to by v.
+// It returns io.EOF once all the records have been read, and an error if the sync marker
+// following a block doesn't match the one of the header.
+func (r *OCFReader) Read(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot read into non-pointer or nil %T", v)
+	}
+
+	if !r.ocf.Scan() {
+		if err := r.ocf.Err(); err != nil {
+			return fmt.Errorf("read block: %w", err)
+		}
+
+		return io.EOF
+	}
+
+	native, err := r.ocf.Read()
+	if err != nil {
+		return fmt.Errorf("read record: %w", err)
+	}
+
+	return r.c.assign(native, rv.Elem())
+}
@@ -0,0 +1,330 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOCFWriter(t *testing.T) {
+	schema, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, schema)
+	assert.NoError(t, err)
+
+	for i := 0; i < ocfBlockLength+1; i++ {
+		assert.NoError(t, w.Append(Person{Name: "Jane", Age: int32(i)}))
+	}
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []byte("Obj\x01"), buf.Bytes()[:4])
+
+	r, err := goavro.NewOCFReader(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, schema, string(r.MetaData()["avro.schema"]))
+
+	var count int
+	for r.Scan() {
+		native, err := r.Read()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"name": "Jane", "age": int32(count)}, native)
+
+		count++
+	}
+
+	assert.NoError(t, r.Err())
+	assert.Equal(t, ocfBlockLength+1, count)
+}
+
+func TestOCFWriter_errors(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewOCFWriter(&buf, `{"type":"record"}`)
+	assert.Error(t, err)
+
+	w, err := NewOCFWriter(&buf, `"string"`)
+	assert.NoError(t, err)
+	assert.Error(t, w.Append(42))
+	assert.NoError(t, w.Close())
+	assert.Error(t, w.Append("closed"))
+}
+
+func TestOCFReader(t *testing.T) {
+	schema, err := InferSchema("avro", Order{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, schema)
+	assert.NoError(t, err)
+
+	coupon := "WELCOME"
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []Order{
+		{ID: 1, Customer: "jane", Coupon: &coupon, Status: "RED", Items: []OrderItem{}, Labels: map[string]string{},
+			Created: created, Total: *big.NewRat(1, 2)},
+		{ID: 2, Customer: "john", Status: "BLUE", Items: []OrderItem{{Sku: "A-1", Quantity: 1}}, Labels: map[string]string{"a": "b"},
+			Created: created, Total: *big.NewRat(3, 4)},
+	}
+
+	for _, order := range want {
+		assert.NoError(t, w.Append(order))
+	}
+
+	assert.NoError(t, w.Close())
+
+	r, err := NewOCFReader(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, schema, r.Schema())
+	assert.Equal(t, "null", r.CompressionName())
+
+	var got []Order
+	for {
+		var order Order
+		err := r.Read(&order)
+		if err == io.EOF {
+			break
+		}
+
+		if !assert.NoError(t, err) {
+			break
+		}
+
+		got = append(got, order)
+	}
+
+	assert.Equal(t, want, got)
+}
+
+type Heartbeat struct {
+	Sent     time.Time  `avro:"sent"`
+	Received time.Time  `avro:"received,logicalType=timestamp-micros"`
+	Acked    *time.Time `avro:"acked"`
+	Raw      time.Time  `avro:"raw,type=long"`
+}
+
+func TestOCFReader_timestamps(t *testing.T) {
+	schema, err := InferSchema("avro", Heartbeat{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Heartbeat","type":"record","fields":[`+
+		`{"name":"sent","type":{"type":"long","logicalType":"timestamp-millis"}},`+
+		`{"name":"received","type":{"type":"long","logicalType":"timestamp-micros"}},`+
+		`{"name":"acked","type":["null",{"type":"long","logicalType":"timestamp-millis"}],"default":null},`+
+		`{"name":"raw","type":"long"}]}`, schema)
+
+	at := time.Date(2024, 5, 1, 8, 9, 10, 123456789, time.FixedZone("CEST", 2*60*60))
+	beat := Heartbeat{Sent: at, Received: at, Acked: &at, Raw: at}
+
+	// the times are decoded in UTC, to the precision of their logical type
+	millis, micros := at.Truncate(time.Millisecond).UTC(), at.Truncate(time.Microsecond).UTC()
+	want := Heartbeat{Sent: millis, Received: micros, Acked: &millis, Raw: millis}
+
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, schema)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Append(beat))
+	assert.NoError(t, w.Close())
+
+	r, err := NewOCFReader(&buf)
+	assert.NoError(t, err)
+
+	var got Heartbeat
+	assert.NoError(t, r.Read(&got))
+	assert.Equal(t, want, got)
+
+	message, err := SingleObjectEncode(schema, beat)
+	assert.NoError(t, err)
+
+	got = Heartbeat{}
+	assert.NoError(t, SingleObjectDecode(schema, message, &got))
+	assert.Equal(t, want, got)
+
+	b, err := Marshal(schema, beat)
+	assert.NoError(t, err)
+
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(b)
+	assert.NoError(t, err)
+	assert.Equal(t, at.UnixMilli(), native.(map[string]interface{})["raw"])
+}
+
+func TestOCFReader_sync_marker(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, `"string"`)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Append("hello"))
+	assert.NoError(t, w.Close())
+
+	// corrupt the sync marker following the block
+	b := buf.Bytes()
+	b[len(b)-1] ^= 0xff
+
+	r, err := NewOCFReader(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	var s string
+	err = r.Read(&s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "sync marker mismatch")
+	}
+}
+
+func TestOCFWriter_deflate(t *testing.T) {
+	schema, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, schema, WithCodec("deflate"))
+	assert.NoError(t, err)
+
+	want := make([]Person, 100)
+	for i := range want {
+		want[i] = Person{Name: "Jane Doe", Age: int32(i)}
+		assert.NoError(t, w.Append(want[i]))
+	}
+
+	assert.NoError(t, w.Close())
+
+	r, err := NewOCFReader(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "deflate", r.CompressionName())
+	assert.Equal(t, []byte("deflate"), r.MetaData()["avro.codec"])
+
+	var got []Person
+	for {
+		var p Person
+		if err := r.Read(&p); err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+
+		got = append(got, p)
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestOCFWriter_unsupported_codec(t *testing.T) {
+	_, err := NewOCFWriter(&bytes.Buffer{}, `"string"`, WithCodec("zstandard"))
+	assert.Error(t, err)
+}
+
+func TestOCFWriter_snappy(t *testing.T) {
+	schema, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewOCFWriter(&buf, schema, WithCodec("snappy"))
+	assert.NoError(t, err)
+
+	want := []Person{{Name: "Jane", Age: 30}, {Name: "John", Age: 40}}
+	for _, p := range want {
+		assert.NoError(t, w.Append(p))
+	}
+
+	assert.NoError(t, w.Close())
+
+	r, err := NewOCFReader(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "snappy", r.CompressionName())
+
+	for _, p := range want {
+		var got Person
+		assert.NoError(t, r.Read(&got))
+		assert.Equal(t, p, got)
+	}
+
+	assert.Equal(t, io.EOF, r.Read(&Person{}))
+}
+
+// snappyOCF returns a snappy Object Container File laid out as the Java avro tools write it:
+// each block is snappy compressed and followed by the big-endian CRC-32 of the uncompressed data.
+func snappyOCF(t *testing.T, schema string, records ...interface{}) []byte {
+	codec, err := goavro.NewCodec(schema)
+	assert.NoError(t, err)
+
+	appendLong := func(b []byte, v int64) []byte {
+		varint := make([]byte, binary.MaxVarintLen64)
+		return append(b, varint[:binary.PutVarint(varint, v)]...)
+	}
+	appendBytes := func(b, v []byte) []byte {
+		return append(appendLong(b, int64(len(v))), v...)
+	}
+
+	sync := []byte("0123456789abcdef")
+
+	b := []byte("Obj\x01")
+	b = appendLong(b, 2)
+	b = appendBytes(b, []byte("avro.schema"))
+	b = appendBytes(b, []byte(schema))
+	b = appendBytes(b, []byte("avro.codec"))
+	b = appendBytes(b, []byte("snappy"))
+	b = appendLong(b, 0)
+	b = append(b, sync...)
+
+	var block []byte
+	for _, record := range records {
+		block, err = codec.BinaryFromNative(block, record)
+		assert.NoError(t, err)
+	}
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(block))
+	data := append(snappy.Encode(nil, block), crc...)
+
+	b = appendLong(b, int64(len(records)))
+	b = appendBytes(b, data)
+
+	return append(b, sync...)
+}
+
+func TestOCFReader_snappy_fixture(t *testing.T) {
+	schema := `{"type":"record","name":"Person","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`
+	file := snappyOCF(t, schema,
+		map[string]interface{}{"name": "Jane", "age": 30},
+		map[string]interface{}{"name": "John", "age": 40},
+	)
+
+	r, err := NewOCFReader(bytes.NewReader(file))
+	assert.NoError(t, err)
+	assert.Equal(t, "snappy", r.CompressionName())
+
+	var got []Person
+	for {
+		var p Person
+		if err := r.Read(&p); err != nil {
+			assert.Equal(t, io.EOF, err)
+			break
+		}
+
+		got = append(got, p)
+	}
+
+	assert.Equal(t, []Person{{Name: "Jane", Age: 30}, {Name: "John", Age: 40}}, got)
+}
+
+func TestOCFReader_snappy_crc(t *testing.T) {
+	file := snappyOCF(t, `"string"`, "avrocado")
+
+	// the CRC precedes the 16 bytes of the trailing sync marker
+	file[len(file)-17] ^= 0xff
+
+	r, err := NewOCFReader(bytes.NewReader(file))
+	assert.NoError(t, err)
+
+	var s string
+	err = r.Read(&s)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	}
+}
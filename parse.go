@@ -0,0 +1,356 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSchema parses the JSON avro schema into a TypedSchema tree, the same as the one inferred by InferSchemaTree.
+//
+// The named types are defined once and referenced by their full name afterwards, the primitive types declared
+// as objects are reduced to their name and a field default of null is parsed as Null.
+// The custom attributes are kept in Props.
+func ParseSchema(schema string) (TypedSchema, error) {
+	s, _, err := parseSchema(schema)
+	if err != nil {
+		return TypedSchema{}, fmt.Errorf("parse schema: %w", err)
+	}
+
+	return s, nil
+}
+
+// schemaParser turns a decoded JSON avro schema into a TypedSchema tree.
+type schemaParser struct {
+	// named holds the named types by full name
+	named map[string]TypedSchema
+	// records holds the full names of the records, in definition order
+	records []string
+}
+
+// parseSchema decodes and parses the JSON avro schema.
+func parseSchema(schema string) (TypedSchema, *schemaParser, error) {
+	dec := json.NewDecoder(strings.NewReader(schema))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return TypedSchema{}, nil, fmt.Errorf("decode schema: %w", err)
+	}
+
+	p := &schemaParser{named: make(map[string]TypedSchema)}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		s, err := p.parseObject(m, "")
+		return s, p, err
+	}
+
+	typ, err := p.parse(v, "")
+
+	return TypedSchema{Type: typ}, p, err
+}
+
+// parse parses the schema v declared within namespace into a type name, a union or a TypedSchema.
+func (p *schemaParser) parse(v interface{}, namespace string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return p.resolve(v, namespace)
+
+	case []interface{}:
+		union := make([]interface{}, 0, len(v))
+
+		for _, branch := range v {
+			typ, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("union: %w", err)
+			}
+
+			union = append(union, typ)
+		}
+
+		if err := checkUnion(union); err != nil {
+			return nil, err
+		}
+
+		return union, nil
+
+	case map[string]interface{}:
+		s, err := p.parseObject(v, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.schema(), nil
+	}
+
+	return nil, fmt.Errorf("unexpected schema %v of type %T", v, v)
+}
+
+// unionBranch returns what identifies the parsed type typ within a union: the full name of a named type,
+// or the type name for the other ones. It returns an empty string for a union.
+func unionBranch(typ interface{}) string {
+	switch typ := typ.(type) {
+	case string:
+		return typ
+	case TypedSchema:
+		if isNamed(typ) {
+			return AddNamespace(typ.Namespace, typ.Name)
+		}
+
+		return unionBranch(typ.Type)
+	}
+
+	return ""
+}
+
+// resolve returns the primitive type name, or the full name of the named type referenced by name.
+func (p *schemaParser) resolve(name, namespace string) (string, error) {
+	if isPrimitive(name) {
+		return name, nil
+	}
+
+	if full := fullName(name, "", namespace); p.named[full].Type != nil {
+		return full, nil
+	}
+
+	if p.named[name].Type != nil {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("unknown type %q", name)
+}
+
+// parseObject parses a schema declared as a JSON object.
+func (p *schemaParser) parseObject(m map[string]interface{}, namespace string) (s TypedSchema, err error) {
+	if _, ok := m["type"]; !ok {
+		return s, errors.New("missing type")
+	}
+
+	s.Doc, _ = m["doc"].(string)
+	s.LogicalType, _ = m["logicalType"].(string)
+	s.Props = customAttributes(m)
+
+	if s.Precision, err = intAttribute(m, "precision"); err != nil {
+		return s, err
+	}
+
+	if s.Scale, err = intAttribute(m, "scale"); err != nil {
+		return s, err
+	}
+
+	typ, ok := m["type"].(string)
+	if !ok {
+		if s.Type, err = p.parse(m["type"], namespace); err != nil {
+			return s, err
+		}
+
+		return s, nil
+	}
+
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		s.Type = typ
+		if err = p.define(&s, m, namespace); err != nil {
+			return s, err
+		}
+
+	case "array":
+		s.Type = typ
+		if s.Items, err = p.parse(m["items"], namespace); err != nil {
+			return s, fmt.Errorf("array: %w", err)
+		}
+
+	case "map":
+		s.Type = typ
+		if s.Values, err = p.parse(m["values"], namespace); err != nil {
+			return s, fmt.Errorf("map: %w", err)
+		}
+
+	default:
+		if s.Type, err = p.resolve(typ, namespace); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
+}
+
+// define parses the named type s declared by m and registers it under its full name.
+func (p *schemaParser) define(s *TypedSchema, m map[string]interface{}, namespace string) (err error) {
+	name, _ := m["name"].(string)
+	if name == "" {
+		return fmt.Errorf("%s without a name", s.Type)
+	}
+
+	ns, _ := m["namespace"].(string)
+	full := fullName(name, ns, namespace)
+
+	if !isFullName(full) {
+		return fmt.Errorf("invalid name %q", full)
+	}
+
+	if isPrimitive(full) {
+		return fmt.Errorf("%s can't be named after the primitive type %s", s.Type, full)
+	}
+
+	if _, ok := p.named[full]; ok {
+		return fmt.Errorf("%s redefined", full)
+	}
+
+	s.Name = full[strings.LastIndex(full, ".")+1:]
+	s.Namespace = namespaceOf(full)
+	s.Aliases = stringsAttribute(m, "aliases")
+
+	// the name is defined before the fields are parsed, so that recursive records can reference it
+	p.named[full] = *s
+
+	switch s.Type {
+	case "record", "error":
+		p.records = append(p.records, full)
+
+		fields, ok := m["fields"].([]interface{})
+		if !ok {
+			return fmt.Errorf("record %s without fields", full)
+		}
+
+		names := make(map[string]bool, len(fields))
+
+		for _, f := range fields {
+			field, ok := f.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("unexpected field %v of %s", f, full)
+			}
+
+			parsed, err := p.parseField(field, s.Namespace)
+			if err != nil {
+				return fmt.Errorf("record %s: %w", full, err)
+			}
+
+			if names[parsed.Name] {
+				return fmt.Errorf("record %s: duplicate field %s", full, parsed.Name)
+			}
+
+			names[parsed.Name] = true
+			s.Fields = append(s.Fields, parsed)
+		}
+
+	case "enum":
+		s.Symbols = stringsAttribute(m, "symbols")
+		if len(s.Symbols) == 0 {
+			return fmt.Errorf("enum %s without symbols", full)
+		}
+
+		symbols := make(map[string]bool, len(s.Symbols))
+		for _, symbol := range s.Symbols {
+			if !avroNameRegexp.MatchString(symbol) {
+				return fmt.Errorf("enum %s: invalid symbol %q", full, symbol)
+			}
+
+			if symbols[symbol] {
+				return fmt.Errorf("enum %s: duplicate symbol %s", full, symbol)
+			}
+
+			symbols[symbol] = true
+		}
+
+		if def, ok := m["default"].(string); ok {
+			if !symbols[def] {
+				return fmt.Errorf("enum %s: default %q is not one of its symbols", full, def)
+			}
+
+			s.Default = def
+		}
+
+	case "fixed":
+		if s.Size, err = intAttribute(m, "size"); err != nil {
+			return err
+		}
+
+		if s.Size <= 0 {
+			return fmt.Errorf("fixed %s size must be positive", full)
+		}
+	}
+
+	p.named[full] = *s
+
+	return nil
+}
+
+// parseField parses a record field.
+func (p *schemaParser) parseField(m map[string]interface{}, namespace string) (f TypedSchema, err error) {
+	f.Name, _ = m["name"].(string)
+	if f.Name == "" {
+		return f, errors.New("field without a name")
+	}
+
+	if !avroNameRegexp.MatchString(f.Name) {
+		return f, fmt.Errorf("invalid field name %q", f.Name)
+	}
+
+	f.Doc, _ = m["doc"].(string)
+	f.Aliases = stringsAttribute(m, "aliases")
+	f.Order, _ = m["order"].(string)
+	f.Props = customAttributes(m)
+
+	if def, ok := m["default"]; ok {
+		f.Default = def
+		if def == nil {
+			f.Default = Null{}
+		}
+	}
+
+	if f.Type, err = p.parse(m["type"], namespace); err != nil {
+		return f, fmt.Errorf("field %s: %w", f.Name, err)
+	}
+
+	return f, nil
+}
+
+// customAttributes returns the attributes of m which aren't standard ones, or nil if there are none.
+func customAttributes(m map[string]interface{}) map[string]interface{} {
+	var props map[string]interface{}
+	for key, v := range m {
+		if reservedAttributes[key] {
+			continue
+		}
+
+		if props == nil {
+			props = make(map[string]interface{})
+		}
+
+		props[key] = v
+	}
+
+	return props
+}
+
+// intAttribute returns the integer attribute key of m, or 0 if it isn't set.
+func intAttribute(m map[string]interface{}, key string) (int, error) {
+	v, ok := m[key]
+	if !ok {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(fmt.Sprint(v))
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got %v", key, v)
+	}
+
+	return i, nil
+}
+
+// stringsAttribute returns the string array attribute key of m.
+func stringsAttribute(m map[string]interface{}, key string) []string {
+	values, _ := m[key].([]interface{})
+
+	var strs []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+
+	return strs
+}
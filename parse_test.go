@@ -0,0 +1,109 @@
+package avro
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchema(t *testing.T) {
+	got, err := ParseSchema(`{"type":"record","name":"Customer","namespace":"com.acme","doc":"A customer","fields":[
+		{"name":"name","type":{"type":"string"}},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"since","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"tier","type":{"type":"enum","name":"Tier","symbols":["GOLD","SILVER"]},"default":"SILVER"},
+		{"name":"home","type":{"type":"record","name":"Location","fields":[{"name":"city","type":"string"}]}},
+		{"name":"work","type":["null","Location"]},
+		{"name":"tags","type":{"type":"array","items":"string"}},
+		{"name":"hashes","type":{"type":"map","values":{"type":"fixed","name":"md5","namespace":"com.acme.hash","size":16}}},
+		{"name":"referrer","type":["null","Customer"],"order":"ignore"}
+	]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, TypedSchema{
+		Name:      "Customer",
+		Namespace: "com.acme",
+		Doc:       "A customer",
+		Type:      "record",
+		Fields: []TypedSchema{
+			{Name: "name", Type: "string"},
+			{Name: "email", Type: []interface{}{"null", "string"}, Default: Null{}},
+			{Name: "since", Type: TypedSchema{Type: "long", LogicalType: "timestamp-millis"}},
+			{Name: "tier", Type: TypedSchema{Name: "Tier", Namespace: "com.acme", Type: "enum", Symbols: []string{"GOLD", "SILVER"}}, Default: "SILVER"},
+			{Name: "home", Type: TypedSchema{Name: "Location", Namespace: "com.acme", Type: "record", Fields: []TypedSchema{{Name: "city", Type: "string"}}}},
+			{Name: "work", Type: []interface{}{"null", "com.acme.Location"}},
+			{Name: "tags", Type: TypedSchema{Type: "array", Items: "string"}},
+			{Name: "hashes", Type: TypedSchema{Type: "map", Values: TypedSchema{Name: "md5", Namespace: "com.acme.hash", Type: "fixed", Size: 16}}},
+			{Name: "referrer", Type: []interface{}{"null", "com.acme.Customer"}, Order: "ignore"},
+		},
+	}, got)
+}
+
+func TestParseSchema_primitives(t *testing.T) {
+	got, err := ParseSchema(`"int"`)
+	assert.NoError(t, err)
+	assert.Equal(t, TypedSchema{Type: "int"}, got)
+
+	got, err = ParseSchema(`["null","string"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, TypedSchema{Type: []interface{}{"null", "string"}}, got)
+}
+
+func TestParseSchema_inferred(t *testing.T) {
+	want, err := InferSchemaTree("avro", Node{})
+	assert.NoError(t, err)
+
+	schema, err := InferSchema("avro", Node{})
+	assert.NoError(t, err)
+
+	got, err := ParseSchema(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestParseSchema_malformed(t *testing.T) {
+	tests := map[string]string{
+		"invalid json":           `{"type":`,
+		"missing type":           `{"name":"R"}`,
+		"unknown type":           `{"type":"record","name":"R","fields":[{"name":"f","type":"Unknown"}]}`,
+		"duplicate union branch": `["null","string","null"]`,
+		"duplicate named branch": `[{"type":"fixed","name":"F","size":1},"F"]`,
+		"duplicate array branch": `[{"type":"array","items":"int"},{"type":"array","items":"string"}]`,
+		"nested union":           `["null",["int","string"]]`,
+		"record without name":    `{"type":"record","fields":[]}`,
+		"record without fields":  `{"type":"record","name":"R"}`,
+		"redefined record":       `{"type":"record","name":"R","fields":[{"name":"r","type":{"type":"record","name":"R","fields":[]}}]}`,
+		"duplicate field":        `{"type":"record","name":"R","fields":[{"name":"f","type":"int"},{"name":"f","type":"int"}]}`,
+		"invalid field name":     `{"type":"record","name":"R","fields":[{"name":"my-field","type":"int"}]}`,
+		"invalid name":           `{"type":"fixed","name":"my-fixed","size":1}`,
+		"primitive name":         `{"type":"fixed","name":"int","size":1}`,
+		"enum without symbols":   `{"type":"enum","name":"E","symbols":[]}`,
+		"duplicate symbol":       `{"type":"enum","name":"E","symbols":["A","A"]}`,
+		"invalid enum default":   `{"type":"enum","name":"E","symbols":["A"],"default":"B"}`,
+		"invalid fixed size":     `{"type":"fixed","name":"F","size":0}`,
+	}
+
+	for name, schema := range tests {
+		_, err := ParseSchema(schema)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestParseSchema_props(t *testing.T) {
+	schema := `{"name":"Payment","type":"record","fields":[{"name":"card","type":{"type":"string","x-format":"pan"},"x-pii":true}],"x-team":"payments"}`
+
+	got, err := ParseSchema(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, TypedSchema{
+		Name:  "Payment",
+		Type:  "record",
+		Props: map[string]interface{}{"x-team": "payments"},
+		Fields: []TypedSchema{
+			{Name: "card", Type: TypedSchema{Type: "string", Props: map[string]interface{}{"x-format": "pan"}}, Props: map[string]interface{}{"x-pii": true}},
+		},
+	}, got)
+
+	b, err := json.Marshal(got)
+	assert.NoError(t, err)
+	assert.Equal(t, schema, string(b))
+}
@@ -0,0 +1,82 @@
+package avro
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// protobufName returns the name of a field of a protobuf generated struct: the name= option of its protobuf tag,
+// as in protobuf:"bytes,1,opt,name=user_id,proto3", or the name of the oneof it holds. protoc-gen-go writes the same
+// name in the json tag, so the names don't depend on the fallback tags the schema was inferred with.
+func protobufName(field reflect.StructField) string {
+	if name, ok := field.Tag.Lookup("protobuf_oneof"); ok {
+		return name
+	}
+
+	for _, opt := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(opt, "name=") {
+			return strings.TrimPrefix(opt, "name=")
+		}
+	}
+
+	return ""
+}
+
+// isProtobufInternal tells if the field is one of the XXX_ fields the older protoc-gen-go adds to the messages
+// for its own bookkeeping, such as XXX_unrecognized.
+func isProtobufInternal(field reflect.StructField) bool {
+	return strings.HasPrefix(field.Name, "XXX_") && field.Tag.Get("json") == "-"
+}
+
+// isOneof tells if the field holds a oneof, nil when none of its fields is set.
+func isOneof(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("protobuf_oneof")
+	return ok
+}
+
+// protobufTime is implemented by the protobuf timestamps, such as timestamppb.Timestamp.
+type protobufTime interface {
+	AsTime() time.Time
+}
+
+var protobufTimeType = reflect.TypeOf((*protobufTime)(nil)).Elem()
+
+// messageTime returns the time of the value v of a protobuf timestamp, which implements AsTime on its pointer.
+func messageTime(v reflect.Value) (time.Time, bool) {
+	if v.Kind() != reflect.Struct || !v.CanInterface() {
+		return time.Time{}, false
+	}
+
+	// the message is copied when it can't be addressed, such as within an interface
+	p := reflect.New(v.Type())
+	if v.CanAddr() {
+		p = v.Addr()
+	} else {
+		p.Elem().Set(v)
+	}
+
+	m, ok := p.Interface().(protobufTime)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return m.AsTime(), true
+}
+
+// setMessageTime sets the Seconds and Nanos fields of the protobuf timestamp dst to t, and tells if dst is one.
+func setMessageTime(dst reflect.Value, t time.Time) bool {
+	if dst.Kind() != reflect.Struct || !dst.CanAddr() || !dst.Addr().Type().Implements(protobufTimeType) {
+		return false
+	}
+
+	seconds, nanos := dst.FieldByName("Seconds"), dst.FieldByName("Nanos")
+	if seconds.Kind() != reflect.Int64 || nanos.Kind() != reflect.Int32 || !seconds.CanSet() || !nanos.CanSet() {
+		return false
+	}
+
+	seconds.SetInt(t.Unix())
+	nanos.SetInt(int64(t.Nanosecond()))
+
+	return true
+}
@@ -0,0 +1,100 @@
+package avro
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Timestamp mimics google.golang.org/protobuf/types/known/timestamppb.Timestamp.
+type Timestamp struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Nanos   int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+}
+
+func (x *Timestamp) AsTime() time.Time {
+	return time.Unix(x.Seconds, int64(x.Nanos)).UTC()
+}
+
+// Purchase is laid out as protoc-gen-go would generate it for:
+//
+//	message Purchase {
+//	  string purchase_id = 1;
+//	  google.protobuf.Timestamp created_at = 2;
+//	  oneof payment {
+//	    string card = 3;
+//	    string iban = 4;
+//	  }
+//	}
+type Purchase struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	PurchaseId string     `protobuf:"bytes,1,opt,name=purchase_id,json=purchaseId,proto3" json:"purchase_id,omitempty"`
+	CreatedAt  *Timestamp `protobuf:"bytes,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Types that are assignable to Payment:
+	//	*Purchase_Card
+	//	*Purchase_Iban
+	Payment isPurchase_Payment `protobuf_oneof:"payment"`
+
+	XXX_unrecognized []byte `json:"-"`
+}
+
+type isPurchase_Payment interface {
+	isPurchase_Payment()
+}
+
+type Purchase_Card struct {
+	Card string `protobuf:"bytes,3,opt,name=card,proto3,oneof"`
+}
+
+type Purchase_Iban struct {
+	Iban string `protobuf:"bytes,4,opt,name=iban,proto3,oneof"`
+}
+
+func (*Purchase_Card) isPurchase_Payment() {}
+
+func (*Purchase_Iban) isPurchase_Payment() {}
+
+func TestInferSchema_protobuf(t *testing.T) {
+	RegisterTypeName("github.com/leboncoin/avrocado.Timestamp",
+		typeRegistry.names["google.golang.org/protobuf/types/known/timestamppb.Timestamp"])
+	defer RegisterTypeName("github.com/leboncoin/avrocado.Timestamp", nil)
+
+	payment := reflect.TypeOf((*isPurchase_Payment)(nil)).Elem()
+	RegisterUnion(payment, &Purchase_Card{}, &Purchase_Iban{})
+	defer RegisterUnion(payment)
+
+	schema, err := InferSchema("json", Purchase{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Purchase","type":"record","fields":[`+
+		`{"name":"purchase_id","type":"string"},`+
+		`{"name":"created_at","type":["null",{"type":"long","logicalType":"timestamp-micros"}],"default":null},`+
+		`{"name":"payment","type":["null",`+
+		`{"name":"Purchase_Card","type":"record","fields":[{"name":"card","type":"string"}]},`+
+		`{"name":"Purchase_Iban","type":"record","fields":[{"name":"iban","type":"string"}]}],"default":null}]}`, schema)
+
+	// the protobuf tags name the fields without the json fallback, as they do when marshaling
+	withoutFallback, err := InferSchema("avro", Purchase{})
+	assert.NoError(t, err)
+	assert.Equal(t, schema, withoutFallback)
+
+	for _, want := range []Purchase{
+		{PurchaseId: "42", CreatedAt: &Timestamp{Seconds: 1600000000, Nanos: 123456000}, Payment: &Purchase_Iban{Iban: "FR76"}},
+		{PurchaseId: "43"},
+	} {
+		data, err := Marshal(schema, want)
+		assert.NoError(t, err)
+
+		var got Purchase
+		assert.NoError(t, Unmarshal(schema, data, &got))
+		assert.Equal(t, want, got)
+	}
+}
@@ -0,0 +1,457 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// UnmarshalResolving decodes the avro binary data written with the writer schema into the Go value pointed to by v,
+// resolving it to the reader schema first as the avro specification describes: the fields are matched by name
+// or alias whatever their order, the reader fields missing from the writer take their default, the writer fields
+// missing from the reader are skipped and the numbers are promoted from int to long, float and double.
+// The logical types which don't match are ignored, such as a long read as a timestamp-millis.
+//
+// The value is then decoded for the reader schema as Unmarshal does.
+func UnmarshalResolving(writer, reader string, data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal into non-pointer or nil %T", v)
+	}
+
+	w, err := compileSchema(writer)
+	if err != nil {
+		return fmt.Errorf("writer: %w", err)
+	}
+
+	r, err := compileSchema(reader)
+	if err != nil {
+		return fmt.Errorf("reader: %w", err)
+	}
+
+	native, _, err := w.codec.NativeFromBinary(data)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	res := resolver{reader: r.named, writer: w.named}
+
+	resolved, err := res.resolve(r.root, w.root, native, "")
+	if err != nil {
+		return err
+	}
+
+	return r.assign(resolved, rv.Elem())
+}
+
+// resolver turns the goavro native value of a parsed writer schema into the one of a parsed reader schema.
+type resolver struct {
+	reader map[string]TypedSchema
+	writer map[string]TypedSchema
+}
+
+// resolve returns the native value of the reader type r for the native value written with the writer type w.
+func (res *resolver) resolve(r, w interface{}, native interface{}, path string) (interface{}, error) {
+	r, w = unionNode(r), unionNode(w)
+
+	if branches, ok := w.([]interface{}); ok {
+		if native == nil {
+			w = "null"
+		} else {
+			branch, value, err := unionValue(branches, native)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", pathOrRoot(path), err)
+			}
+
+			w, native = branch, value
+		}
+	}
+
+	if branches, ok := r.([]interface{}); ok {
+		// the first branch which can read the written type is chosen
+		for _, branch := range branches {
+			checker := compatChecker{reader: res.reader, writer: res.writer, visited: make(map[[2]string]bool)}
+			if checker.check(branch, w, path) != nil {
+				continue
+			}
+
+			value, err := res.resolve(branch, w, native, path)
+			if err != nil || value == nil {
+				return value, err
+			}
+
+			return map[string]interface{}{goavroName(branch): value}, nil
+		}
+
+		return nil, fmt.Errorf("%s: %s can't be read as any branch of the union", pathOrRoot(path), typeName(resolveNode(w, res.writer)))
+	}
+
+	rl, wl := nativeLogicalType(r, res.reader), nativeLogicalType(w, res.writer)
+	if rl != wl {
+		return res.resolveUnderlying(r, w, native, path)
+	}
+
+	rs, ws := resolveNode(r, res.reader), resolveNode(w, res.writer)
+
+	if rl != "" && rs.Type == ws.Type {
+		return native, nil
+	}
+
+	if rs.Type != ws.Type {
+		return promote(native, fmt.Sprint(rs.Type), fmt.Sprint(ws.Type), path)
+	}
+
+	switch rs.Type {
+	case "record", "error":
+		if !sameName(rs, ws) {
+			return nil, fmt.Errorf("%s: %s can't be read as %s", pathOrRoot(path), typeName(ws), typeName(rs))
+		}
+
+		return res.resolveRecord(rs, ws, native, path)
+
+	case "enum":
+		if !sameName(rs, ws) {
+			return nil, fmt.Errorf("%s: %s can't be read as %s", pathOrRoot(path), typeName(ws), typeName(rs))
+		}
+
+		symbol, _ := native.(string)
+		if isSymbol(rs.Symbols, symbol) {
+			return symbol, nil
+		}
+
+		if def, ok := rs.Default.(string); ok {
+			return def, nil
+		}
+
+		return nil, fmt.Errorf("%s: symbol %s is missing from the reader enum %s, which has no default", pathOrRoot(path), symbol, typeName(rs))
+
+	case "fixed":
+		if !sameName(rs, ws) || rs.Size != ws.Size {
+			return nil, fmt.Errorf("%s: %s can't be read as %s", pathOrRoot(path), typeName(ws), typeName(rs))
+		}
+
+	case "array":
+		items, _ := native.([]interface{})
+
+		resolved := make([]interface{}, len(items))
+		for i, item := range items {
+			value, err := res.resolve(rs.Items, ws.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[i] = value
+		}
+
+		return resolved, nil
+
+	case "map":
+		values, _ := native.(map[string]interface{})
+
+		resolved := make(map[string]interface{}, len(values))
+		for key, v := range values {
+			value, err := res.resolve(rs.Values, ws.Values, v, fmt.Sprintf("%s[%q]", path, key))
+			if err != nil {
+				return nil, err
+			}
+
+			resolved[key] = value
+		}
+
+		return resolved, nil
+	}
+
+	return native, nil
+}
+
+// resolveRecord returns the native record of the reader record r for the native record written with the writer record w.
+func (res *resolver) resolveRecord(r, w TypedSchema, native interface{}, path string) (interface{}, error) {
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected record value %v", pathOrRoot(path), native)
+	}
+
+	resolved := make(map[string]interface{}, len(r.Fields))
+
+	for _, rf := range r.Fields {
+		var (
+			value interface{}
+			err   error
+		)
+
+		if wf, ok := writerField(rf, w); ok {
+			value, err = res.resolve(rf.Type, wf.Type, record[wf.Name], joinPath(path, rf.Name))
+		} else if rf.Default != nil {
+			value, err = res.defaultNative(rf.Type, rf.Default, joinPath(path, rf.Name))
+		} else {
+			err = fmt.Errorf("%s: field missing from the writer has no default", joinPath(path, rf.Name))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		resolved[rf.Name] = value
+	}
+
+	return resolved, nil
+}
+
+// promote returns the native value of the writer primitive type w promoted to the reader primitive type r.
+func promote(native interface{}, r, w string, path string) (interface{}, error) {
+	switch v := native.(type) {
+	case int32:
+		switch r {
+		case "long":
+			return int64(v), nil
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+
+	case int64:
+		switch r {
+		case "float":
+			return float32(v), nil
+		case "double":
+			return float64(v), nil
+		}
+
+	case float32:
+		if r == "double" {
+			return float64(v), nil
+		}
+
+	case string:
+		if r == "bytes" {
+			return []byte(v), nil
+		}
+
+	case []byte:
+		if r == "string" {
+			return string(v), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %s can't be read as %s", pathOrRoot(path), w, r)
+}
+
+// resolveUnderlying resolves the native value of the writer type w to the reader type r of a different logical type
+// on their underlying types, as the avro specification ignores the logical types which don't match: the value is
+// turned into the one of the underlying writer type, resolved, and turned into the one of the reader logical type.
+func (res *resolver) resolveUnderlying(r, w interface{}, native interface{}, path string) (interface{}, error) {
+	writer, reader := validator{named: res.writer}, validator{named: res.reader}
+	uw, ur := underlyingType(w, res.writer), underlyingType(r, res.reader)
+
+	native, err := writer.recode(w, uw, native)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pathOrRoot(path), err)
+	}
+
+	value, err := res.resolve(ur, uw, native, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, err = reader.recode(ur, r, value); err != nil {
+		return nil, fmt.Errorf("%s: %w", pathOrRoot(path), err)
+	}
+
+	return value, nil
+}
+
+// underlyingType returns the parsed type typ without its logical type.
+func underlyingType(typ interface{}, named map[string]TypedSchema) interface{} {
+	s, ok := typ.(TypedSchema)
+	if !ok {
+		if name, ok := typ.(string); ok {
+			s, ok = named[name]
+		}
+
+		if !ok {
+			return typ
+		}
+	}
+
+	if s.LogicalType == "" {
+		return typ
+	}
+
+	s.LogicalType, s.Precision, s.Scale = "", 0, 0
+
+	return s
+}
+
+// recode turns the native value of the parsed type from into the one of the parsed type to, both sharing the same
+// binary encoding, such as a logical type and its underlying type.
+func (val *validator) recode(from, to interface{}, native interface{}) (interface{}, error) {
+	if reflect.DeepEqual(from, to) {
+		return native, nil
+	}
+
+	_, encoder, err := val.standaloneCodec(from)
+	if err != nil {
+		return nil, err
+	}
+
+	_, decoder, err := val.standaloneCodec(to)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := encoder.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _, err := decoder.NativeFromBinary(b)
+
+	return value, err
+}
+
+// nativeLogicalType returns the logical type of the parsed type typ if goavro decodes it to a specific Go type,
+// such as time.Time for the timestamps.
+func nativeLogicalType(typ interface{}, named map[string]TypedSchema) string {
+	s, ok := typ.(TypedSchema)
+	if !ok {
+		if name, ok := typ.(string); ok {
+			s = named[name]
+		}
+	}
+
+	switch s.LogicalType {
+	case "timestamp-millis", "timestamp-micros", "time-millis", "time-micros", "date", "decimal":
+		return s.LogicalType
+	}
+
+	return ""
+}
+
+// defaultNative returns the native value of the default def of a field of the reader type typ.
+// The default of a union is a value of its first branch.
+func (res *resolver) defaultNative(typ interface{}, def interface{}, path string) (interface{}, error) {
+	if branches, ok := unionNode(typ).([]interface{}); ok {
+		value, err := res.defaultNative(branches[0], def, path)
+		if err != nil || value == nil {
+			return value, err
+		}
+
+		return map[string]interface{}{goavroName(branches[0]): value}, nil
+	}
+
+	s := resolveNode(typ, res.reader)
+	invalid := fmt.Errorf("%s: invalid default %v of %s", pathOrRoot(path), def, typeName(s))
+
+	switch s.Type {
+	case "null":
+		return nil, nil
+
+	case "boolean":
+		if b, ok := def.(bool); ok {
+			return b, nil
+		}
+
+	case "int", "long", "float", "double":
+		var (
+			value interface{}
+			err   error
+			n     = fmt.Sprint(def)
+		)
+
+		switch s.Type {
+		case "int":
+			var i int64
+			i, err = strconv.ParseInt(n, 10, 32)
+			value = int32(i)
+		case "long":
+			value, err = strconv.ParseInt(n, 10, 64)
+		case "float":
+			var f float64
+			f, err = strconv.ParseFloat(n, 32)
+			value = float32(f)
+		default:
+			value, err = strconv.ParseFloat(n, 64)
+		}
+
+		if err != nil {
+			return nil, invalid
+		}
+
+		return value, nil
+
+	case "string", "enum":
+		if str, ok := def.(string); ok {
+			return str, nil
+		}
+
+	case "bytes", "fixed":
+		// the default of bytes is a string whose code points are the bytes
+		if str, ok := def.(string); ok {
+			b := make([]byte, 0, len(str))
+			for _, r := range str {
+				if r > 0xff {
+					return nil, invalid
+				}
+
+				b = append(b, byte(r))
+			}
+
+			return b, nil
+		}
+
+	case "array":
+		if items, ok := def.([]interface{}); ok {
+			values := make([]interface{}, len(items))
+			for i, item := range items {
+				value, err := res.defaultNative(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+
+				values[i] = value
+			}
+
+			return values, nil
+		}
+
+	case "map":
+		if m, ok := def.(map[string]interface{}); ok {
+			values := make(map[string]interface{}, len(m))
+			for key, item := range m {
+				value, err := res.defaultNative(s.Values, item, fmt.Sprintf("%s[%q]", path, key))
+				if err != nil {
+					return nil, err
+				}
+
+				values[key] = value
+			}
+
+			return values, nil
+		}
+
+	case "record", "error":
+		if m, ok := def.(map[string]interface{}); ok {
+			record := make(map[string]interface{}, len(s.Fields))
+			for _, f := range s.Fields {
+				fieldDef, ok := m[f.Name]
+				if !ok {
+					if fieldDef = f.Default; fieldDef == nil {
+						return nil, fmt.Errorf("%s: default has no value for %s", pathOrRoot(path), f.Name)
+					}
+				}
+
+				value, err := res.defaultNative(f.Type, fieldDef, joinPath(path, f.Name))
+				if err != nil {
+					return nil, err
+				}
+
+				record[f.Name] = value
+			}
+
+			return record, nil
+		}
+	}
+
+	return nil, invalid
+}
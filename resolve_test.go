@@ -0,0 +1,142 @@
+package avro
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const writerUser = `{"type":"record","name":"User","fields":[
+	{"name":"id","type":"int"},
+	{"name":"name","type":"string"},
+	{"name":"legacy","type":"string"},
+	{"name":"score","type":["null","float"]},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BANNED","PENDING"]}}
+]}`
+
+const readerUser = `{"type":"record","name":"User","fields":[
+	{"name":"full_name","type":"string","aliases":["name"]},
+	{"name":"id","type":"long"},
+	{"name":"email","type":"string","default":"unknown"},
+	{"name":"tags","type":{"type":"array","items":"string"},"default":["new"]},
+	{"name":"nickname","type":["null","string"],"default":null},
+	{"name":"score","type":["null","double"]},
+	{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE","BANNED"],"default":"ACTIVE"}}
+]}`
+
+type UserV1 struct {
+	ID     int32    `avro:"id"`
+	Name   string   `avro:"name"`
+	Legacy string   `avro:"legacy"`
+	Score  *float32 `avro:"score"`
+	Status string   `avro:"status"`
+}
+
+type UserV2 struct {
+	FullName string   `avro:"full_name"`
+	ID       int64    `avro:"id"`
+	Email    string   `avro:"email"`
+	Tags     []string `avro:"tags"`
+	Nickname *string  `avro:"nickname"`
+	Score    *float64 `avro:"score"`
+	Status   string   `avro:"status"`
+}
+
+func TestUnmarshalResolving(t *testing.T) {
+	score := float32(1.5)
+
+	data, err := Marshal(writerUser, UserV1{ID: 42, Name: "Bob", Legacy: "dropped", Score: &score, Status: "PENDING"})
+	assert.NoError(t, err)
+
+	var got UserV2
+	assert.NoError(t, UnmarshalResolving(writerUser, readerUser, data, &got))
+
+	expected := 1.5
+	assert.Equal(t, UserV2{FullName: "Bob", ID: 42, Email: "unknown", Tags: []string{"new"}, Score: &expected, Status: "ACTIVE"}, got)
+}
+
+func TestUnmarshalResolving_unions(t *testing.T) {
+	// a branch of the writer union is read as the reader type
+	data, err := Marshal(`["null","int"]`, int32(7))
+	assert.NoError(t, err)
+
+	var l int64
+	assert.NoError(t, UnmarshalResolving(`["null","int"]`, `"long"`, data, &l))
+	assert.Equal(t, int64(7), l)
+
+	data, err = Marshal(`["null","int"]`, nil)
+	assert.NoError(t, err)
+
+	err = UnmarshalResolving(`["null","int"]`, `"long"`, data, &l)
+	assert.EqualError(t, err, "value: null can't be read as long")
+
+	// a writer type is read as the first reader branch which can read it
+	data, err = Marshal(`"int"`, int32(3))
+	assert.NoError(t, err)
+
+	var v interface{}
+	assert.NoError(t, UnmarshalResolving(`"int"`, `["null","string","double"]`, data, &v))
+	assert.Equal(t, float64(3), v)
+}
+
+func TestUnmarshalResolving_logical_types(t *testing.T) {
+	millis := `{"type":"long","logicalType":"timestamp-millis"}`
+	at := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+
+	// a logical type which doesn't match is ignored, the values being resolved on the underlying types
+	data, err := Marshal(`"long"`, at.UnixMilli())
+	assert.NoError(t, err)
+
+	var ts time.Time
+	assert.NoError(t, UnmarshalResolving(`"long"`, millis, data, &ts))
+	assert.True(t, at.Equal(ts), ts)
+
+	data, err = Marshal(millis, at)
+	assert.NoError(t, err)
+
+	var l int64
+	assert.NoError(t, UnmarshalResolving(millis, `"long"`, data, &l))
+	assert.Equal(t, at.UnixMilli(), l)
+
+	// the int is promoted to the long underlying the timestamp
+	data, err = Marshal(`"int"`, int32(1500))
+	assert.NoError(t, err)
+
+	assert.NoError(t, UnmarshalResolving(`"int"`, `{"type":"long","logicalType":"timestamp-micros"}`, data, &ts))
+	assert.True(t, time.UnixMicro(1500).Equal(ts), ts)
+
+	decimal := `{"type":"bytes","logicalType":"decimal","precision":4,"scale":2}`
+
+	data, err = Marshal(decimal, *big.NewRat(1234, 100))
+	assert.NoError(t, err)
+
+	var b []byte
+	assert.NoError(t, UnmarshalResolving(decimal, `"bytes"`, data, &b))
+	assert.Equal(t, []byte{0x04, 0xd2}, b)
+}
+
+func TestUnmarshalResolving_errors(t *testing.T) {
+	data, err := Marshal(writerUser, UserV1{Status: "BANNED"})
+	assert.NoError(t, err)
+
+	var got map[string]interface{}
+
+	err = UnmarshalResolving(writerUser, `{"type":"record","name":"User","fields":[{"name":"email","type":"string"}]}`, data, &got)
+	assert.EqualError(t, err, "email: field missing from the writer has no default")
+
+	err = UnmarshalResolving(writerUser, `{"type":"record","name":"User","fields":[{"name":"id","type":"string"}]}`, data, &got)
+	assert.EqualError(t, err, "id: int can't be read as string")
+
+	err = UnmarshalResolving(writerUser, `{"type":"record","name":"Account","fields":[]}`, data, &got)
+	assert.EqualError(t, err, "value: User can't be read as Account")
+
+	data, err = Marshal(writerUser, UserV1{Status: "PENDING"})
+	assert.NoError(t, err)
+
+	err = UnmarshalResolving(writerUser, `{"type":"record","name":"User","fields":[{"name":"status","type":{"type":"enum","name":"Status","symbols":["ACTIVE"]}}]}`, data, &got)
+	assert.EqualError(t, err, "status: symbol PENDING is missing from the reader enum Status, which has no default")
+
+	assert.EqualError(t, UnmarshalResolving(writerUser, readerUser, data, got), "cannot unmarshal into non-pointer or nil map[string]interface {}")
+}
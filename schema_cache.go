@@ -0,0 +1,30 @@
+package avro
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// schemaCache holds the JSON schemas inferred by inferSchemaFromType, by schemaCacheKey.
+var schemaCache sync.Map
+
+// schemaCacheKey identifies an inferred schema: the type it was inferred from and the options of the inference.
+type schemaCacheKey struct {
+	t       reflect.Type
+	options string
+}
+
+// newSchemaCacheKey returns the key of the schema inferred from t with the given options.
+func newSchemaCacheKey(t reflect.Type, opts inferOptions) schemaCacheKey {
+	return schemaCacheKey{t: t, options: fmt.Sprintf("%#v", opts)}
+}
+
+// ClearSchemaCache empties the cache of the inferred schemas.
+// It is cleared automatically when a type is registered with RegisterType.
+func ClearSchemaCache() {
+	schemaCache.Range(func(key, _ interface{}) bool {
+		schemaCache.Delete(key)
+		return true
+	})
+}
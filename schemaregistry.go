@@ -97,7 +97,7 @@ func (c *ConfluentSchemaRegistry) do(method, urlPath string, in interface{}, out
 	if err != nil {
 		return fmt.Errorf("http.NewRequest error: %w", err)
 	}
-	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
 	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json, application/vnd.schemaregistry+json, application/json")
 
 	resp, err := c.client.Do(req)
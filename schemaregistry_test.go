@@ -35,6 +35,9 @@ func dummyHTTPHandler(t *testing.T, method, path string, status int, reqBody, re
 			t.Errorf("path is wrong, expected `%s`, got `%s`", path, req.URL.Path)
 		}
 		if reqBody != nil {
+			if ct := req.Header.Get("Content-Type"); ct != "application/vnd.schemaregistry.v1+json" {
+				t.Errorf("content type is wrong, expected `application/vnd.schemaregistry.v1+json`, got `%s`", ct)
+			}
 			expbs, err := json.Marshal(reqBody)
 			if err != nil {
 				t.Error(err)
@@ -101,6 +104,26 @@ func TestVersions(t *testing.T) {
 	mustEqual(t, vers, versIn)
 }
 
+func TestRegisterNewSchema(t *testing.T) {
+	s := `{"type":"string"}`
+	c := httpSuccess(t, "POST", "/subjects/mysubject/versions", simpleSchema{s}, map[string]int{"id": 7})
+	id, err := c.RegisterNewSchema("mysubject", s)
+	if err != nil {
+		t.Error(err)
+	}
+	mustEqual(t, id, 7)
+}
+
+func TestGetSchemaByID(t *testing.T) {
+	s := `{"type":"string"}`
+	c := httpSuccess(t, "GET", "/schemas/ids/7", nil, simpleSchema{s})
+	sOut, err := c.GetSchemaByID(7)
+	if err != nil {
+		t.Error(err)
+	}
+	mustEqual(t, sOut, s)
+}
+
 func TestIsRegistered_yes(t *testing.T) {
 	s := `{"x":"y"}`
 	ss := simpleSchema{s}
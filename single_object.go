@@ -0,0 +1,67 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// singleObjectMarker is the 2-byte marker starting a single object encoded message.
+var singleObjectMarker = []byte{0xc3, 0x01}
+
+// singleObjectHeaderLength is the length of the marker and fingerprint prefixing a single object encoded message.
+const singleObjectHeaderLength = 10
+
+// SingleObjectEncode encodes the Go value v with Marshal and frames it in the avro single object encoding:
+// the 0xC3 0x01 marker, followed by the little-endian Rabin fingerprint of the schema and the avro binary data.
+func SingleObjectEncode(schema string, v interface{}) ([]byte, error) {
+	fingerprint, err := FingerprintBytes(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := Marshal(schema, v)
+	if err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, 0, singleObjectHeaderLength+len(data))
+	message = append(message, singleObjectMarker...)
+	message = append(message, fingerprint...)
+
+	return append(message, data...), nil
+}
+
+// SingleObjectFingerprint returns the schema fingerprint of a single object encoded message,
+// to look up the schema it was written with.
+func SingleObjectFingerprint(message []byte) (uint64, error) {
+	if len(message) < singleObjectHeaderLength {
+		return 0, fmt.Errorf("message of %d bytes is too short for the %d bytes header", len(message), singleObjectHeaderLength)
+	}
+
+	if !bytes.Equal(message[:2], singleObjectMarker) {
+		return 0, fmt.Errorf("the marker %#x is not correct (expected %#x)", message[:2], singleObjectMarker)
+	}
+
+	return binary.LittleEndian.Uint64(message[2:singleObjectHeaderLength]), nil
+}
+
+// SingleObjectDecode decodes the single object encoded message into the Go value pointed to by v with Unmarshal.
+// The message must have been written with the given schema, as told by its fingerprint.
+func SingleObjectDecode(schema string, message []byte, v interface{}) error {
+	fingerprint, err := SingleObjectFingerprint(message)
+	if err != nil {
+		return err
+	}
+
+	want, err := Fingerprint(schema)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint != want {
+		return fmt.Errorf("message written with the schema of fingerprint %#016x, not %#016x", fingerprint, want)
+	}
+
+	return Unmarshal(schema, message[singleObjectHeaderLength:], v)
+}
@@ -0,0 +1,56 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSingleObjectEncode(t *testing.T) {
+	schema, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+
+	message, err := SingleObjectEncode(schema, Person{Name: "Jane", Age: 42})
+	assert.NoError(t, err)
+
+	fingerprint, err := FingerprintBytes(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte{0xc3, 0x01}, fingerprint...), message[:10])
+	assert.Equal(t, []byte{0x08, 'J', 'a', 'n', 'e', 0x54}, message[10:])
+
+	fp, err := SingleObjectFingerprint(message)
+	assert.NoError(t, err)
+
+	want, err := Fingerprint(schema)
+	assert.NoError(t, err)
+	assert.Equal(t, want, fp)
+
+	var got Person
+	assert.NoError(t, SingleObjectDecode(schema, message, &got))
+	assert.Equal(t, Person{Name: "Jane", Age: 42}, got)
+}
+
+func TestSingleObjectDecode_errors(t *testing.T) {
+	schema, err := InferSchema("avro", Person{})
+	assert.NoError(t, err)
+
+	message, err := SingleObjectEncode(schema, Person{Name: "Jane", Age: 42})
+	assert.NoError(t, err)
+
+	var p Person
+
+	err = SingleObjectDecode(schema, message[:5], &p)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "too short")
+	}
+
+	err = SingleObjectDecode(schema, append([]byte{0xc3, 0x02}, message[2:]...), &p)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "marker")
+	}
+
+	err = SingleObjectDecode(`"string"`, message, &p)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "fingerprint")
+	}
+}
@@ -0,0 +1,472 @@
+package avro
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// Validate checks that the Go value v conforms to the avro schema, following the same conventions as InferSchema
+// to map the struct fields to the record fields.
+//
+// It returns the first mismatch along with the path of the field, e.g. address.zip: expected string, got int.
+func Validate(schema string, v interface{}) error {
+	root, p, err := parseSchema(schema)
+	if err != nil {
+		return fmt.Errorf("parse schema: %w", err)
+	}
+
+	val := validator{
+		named:    p.named,
+		inferrer: &inferrer{named: make(map[string]definition), visiting: make(map[reflect.Type]bool)},
+	}
+
+	return val.validate(root.schema(), reflect.ValueOf(v), "")
+}
+
+// validator walks a Go value along a parsed schema.
+type validator struct {
+	named    map[string]TypedSchema
+	inferrer *inferrer
+}
+
+// validate checks the value v against the parsed schema typ, path being the path of v within the root value.
+func (val *validator) validate(typ interface{}, v reflect.Value, path string) error {
+	// the data written by an AvroMarshaler must be a value of the type
+	if _, ok := typ.([]interface{}); !ok {
+		if m, ok := avroMarshaler(v); ok {
+			_, err := val.marshalAvro(m, typ, path)
+			return err
+		}
+	}
+
+	v = indirect(v, !val.isComposite(typ))
+
+	switch typ := typ.(type) {
+	case string:
+		return val.validateName(typ, v, path)
+
+	case []interface{}:
+		if isNilCollection(v) && hasNull(typ) {
+			return nil
+		}
+
+		for _, branch := range val.unionBranches(typ, v) {
+			if val.validate(branch, v, path) == nil {
+				return nil
+			}
+		}
+
+		names := make([]string, 0, len(typ))
+		for _, branch := range typ {
+			names = append(names, unionBranch(branch))
+		}
+
+		return mismatch(path, "one of "+strings.Join(names, ", "), v)
+
+	case TypedSchema:
+		return val.validateSchema(typ, v, path)
+	}
+
+	return fmt.Errorf("%s: unexpected schema %v", pathOrRoot(path), typ)
+}
+
+// validateName checks the value v against a primitive or named type.
+func (val *validator) validateName(name string, v reflect.Value, path string) error {
+	if name == "null" {
+		if v.IsValid() {
+			return mismatch(path, name, v)
+		}
+
+		return nil
+	}
+
+	if !v.IsValid() {
+		return mismatch(path, name, v)
+	}
+
+	switch name {
+	case "boolean":
+		if v.Kind() != reflect.Bool {
+			return mismatch(path, name, v)
+		}
+
+	case "int":
+		if !isInteger(v) {
+			return mismatch(path, name, v)
+		}
+
+		if !inRange(v, math.MinInt32, math.MaxInt32) {
+			return fmt.Errorf("%s: %v is out of the int range", pathOrRoot(path), v)
+		}
+
+	case "long":
+		if v.Type() == bigIntType {
+			if i := v.Interface().(big.Int); !i.IsInt64() {
+				return fmt.Errorf("%s: %s is out of the long range", pathOrRoot(path), i.String())
+			}
+
+			return nil
+		}
+
+		if !isInteger(v) && v.Type() != timeType {
+			return mismatch(path, name, v)
+		}
+
+		if !inRange(v, math.MinInt64, math.MaxInt64) {
+			return fmt.Errorf("%s: %v is out of the long range", pathOrRoot(path), v)
+		}
+
+	case "float", "double":
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 && v.Type() != bigFloatType {
+			return mismatch(path, name, v)
+		}
+
+	case "bytes":
+		if !isBytes(v) && v.Type() != bigRatType && v.Type() != bigIntType {
+			return mismatch(path, name, v)
+		}
+
+	case "string":
+		if v.Kind() != reflect.String {
+			return mismatch(path, name, v)
+		}
+
+	default:
+		named, ok := val.named[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown type %s", pathOrRoot(path), name)
+		}
+
+		return val.validateSchema(named, v, path)
+	}
+
+	return nil
+}
+
+// validateSchema checks the value v against a complex type.
+func (val *validator) validateSchema(s TypedSchema, v reflect.Value, path string) error {
+	if !v.IsValid() {
+		return mismatch(path, typeName(s), v)
+	}
+
+	if s.LogicalType == "timestamp-millis" || s.LogicalType == "timestamp-micros" {
+		if _, ok := messageTime(v); ok {
+			return nil
+		}
+	}
+
+	if isCalendarType(s.LogicalType) {
+		if v.Type() == durationType && s.LogicalType != "date" {
+			return nil
+		}
+
+		if _, ok, err := calendarTime(s.LogicalType, v); ok {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+	}
+
+	switch s.Type {
+	case "record", "error":
+		return val.validateRecord(s, v, path)
+
+	case "enum":
+		if v.Kind() != reflect.String {
+			return mismatch(path, typeName(s), v)
+		}
+
+		if !isSymbol(s.Symbols, v.String()) {
+			return fmt.Errorf("%s: %q is not a symbol of %s", pathOrRoot(path), v.String(), typeName(s))
+		}
+
+	case "fixed":
+		if s.LogicalType == "decimal" && (v.Type() == bigRatType || v.Type() == bigIntType) {
+			return nil
+		}
+
+		if !isBytes(v) {
+			return mismatch(path, typeName(s), v)
+		}
+
+		if v.Len() != s.Size {
+			return fmt.Errorf("%s: expected %d bytes for %s, got %d", pathOrRoot(path), s.Size, typeName(s), v.Len())
+		}
+
+	case "array":
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return mismatch(path, "array", v)
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			if err := val.validate(s.Items, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "map":
+		if v.Kind() != reflect.Map || !isMapKey(v.Type().Key()) {
+			return mismatch(path, "map", v)
+		}
+
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := val.validate(s.Values, iter.Value(), fmt.Sprintf("%s[%q]", path, mapKey(iter.Key()))); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return val.validate(s.Type, v, path)
+	}
+
+	return nil
+}
+
+// validateRecord checks the struct, or map, v against the record s.
+func (val *validator) validateRecord(s TypedSchema, v reflect.Value, path string) error {
+	fields, err := val.fields(s, v, path)
+	if err != nil {
+		return err
+	}
+
+	for i, f := range s.Fields {
+		if !fields[i].IsValid() && f.Default != nil {
+			continue
+		}
+
+		if err := val.validate(f.Type, fields[i], joinPath(path, f.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fields returns the values of the struct, or map, v matching the fields of the record s.
+// The values of the missing fields are invalid.
+func (val *validator) fields(s TypedSchema, v reflect.Value, path string) ([]reflect.Value, error) {
+	values := make([]reflect.Value, len(s.Fields))
+
+	switch {
+	case v.Kind() == reflect.Struct:
+		indexes, err := val.inferrer.fieldIndexes(v.Type())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pathOrRoot(path), err)
+		}
+
+		for i, f := range s.Fields {
+			index, ok := indexes[f.Name]
+			if !ok {
+				continue
+			}
+
+			// a nil embedded struct pointer leaves the promoted field missing
+			values[i], _ = v.FieldByIndexErr(index)
+		}
+
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for i, f := range s.Fields {
+			values[i] = v.MapIndex(reflect.ValueOf(f.Name).Convert(v.Type().Key()))
+		}
+
+	default:
+		return nil, mismatch(path, typeName(s), v)
+	}
+
+	return values, nil
+}
+
+// indirect dereferences the pointers and interfaces to the underlying value, which is invalid for nil,
+// and replaces the optional wrappers registered with RegisterOptional by their value and the errors by their message.
+// The driver.Valuer values, such as sql.NullString, are replaced by their value when valuers is set.
+func indirect(v reflect.Value, valuers bool) reflect.Value {
+	for v.IsValid() {
+		if v.Type() == errorType && !v.IsNil() && v.CanInterface() {
+			return reflect.ValueOf(v.Interface().(error).Error())
+		}
+
+		if fields, ok := registeredOptional(v.Type()); ok && fields.check(v.Type()) == nil {
+			if !v.FieldByName(fields.present).Bool() {
+				return reflect.Value{}
+			}
+
+			v = v.FieldByName(fields.value)
+			continue
+		}
+
+		if valuers && v.CanInterface() && v.Type() != timeType && v.Type() != bigRatType && v.Type() != bigIntType && v.Type() != bigFloatType {
+			if valuer, ok := v.Interface().(driver.Valuer); ok && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+				value, err := valuer.Value()
+				if err == nil {
+					return indirect(reflect.ValueOf(value), valuers)
+				}
+			}
+		}
+
+		if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface {
+			return v
+		}
+
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// isComposite tells if the parsed type typ, or a branch of the union, is a record, an enum, an array or a map.
+// Their values aren't replaced by the value of their driver.Valuer nor decoded by their sql.Scanner, a struct
+// implementing them for a database column, say as JSON, may have its own record.
+func (val *validator) isComposite(typ interface{}) bool {
+	switch typ := typ.(type) {
+	case string:
+		named, ok := val.named[typ]
+		return ok && val.isComposite(named)
+
+	case []interface{}:
+		for _, branch := range typ {
+			if val.isComposite(branch) {
+				return true
+			}
+		}
+
+	case TypedSchema:
+		switch typ.Type {
+		case "record", "error", "enum", "array", "map":
+			return true
+		case "fixed":
+			return false
+		}
+
+		return val.isComposite(typ.Type)
+	}
+
+	return false
+}
+
+// unionBranches returns the branches of the union in the order they are tried for the value v, the record named
+// after the struct type of v first, so that the implementations of a registered union are told apart even when
+// they have the same fields.
+func (val *validator) unionBranches(union []interface{}, v reflect.Value) []interface{} {
+	if !v.IsValid() || v.Kind() != reflect.Struct || v.Type().Name() == "" {
+		return union
+	}
+
+	for i, branch := range union {
+		s := resolveNode(branch, val.named)
+		if (s.Type == "record" || s.Type == "error") && s.Name == v.Type().Name() {
+			branches := append([]interface{}{branch}, union[:i]...)
+			return append(branches, union[i+1:]...)
+		}
+	}
+
+	return union
+}
+
+// mismatch returns the error of the value v not being of the expected type.
+func mismatch(path, expected string, v reflect.Value) error {
+	got := "nil"
+	if v.IsValid() {
+		got = v.Type().String()
+	}
+
+	return fmt.Errorf("%s: expected %s, got %s", pathOrRoot(path), expected, got)
+}
+
+// pathOrRoot returns path, or a placeholder for the root value.
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "value"
+	}
+
+	return path
+}
+
+// typeName returns the full name of a named type, or the type name of the other ones.
+func typeName(s TypedSchema) string {
+	if isNamed(s) {
+		return AddNamespace(s.Namespace, s.Name)
+	}
+
+	return fmt.Sprint(s.Type)
+}
+
+// isInteger tells if v holds a signed or unsigned integer.
+func isInteger(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+// inRange tells if the integer v is within [min, max]. Values which aren't integers are in range.
+func inRange(v reflect.Value, min, max int64) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() >= min && v.Int() <= max
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() <= uint64(max)
+	}
+
+	return true
+}
+
+// isNilCollection tells if v is a nil slice or map.
+func isNilCollection(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil()
+}
+
+// hasNull tells if the union has a null branch.
+func hasNull(union []interface{}) bool {
+	for _, branch := range union {
+		if branch == "null" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isMapKey tells if the Go map key type t can be turned into an avro map key: a string, an integer or a fmt.Stringer.
+func isMapKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return t.Implements(stringerType)
+}
+
+// mapKey returns the avro map key of the Go map key v, the integers being written in base 10.
+func mapKey(v reflect.Value) string {
+	switch {
+	case v.Kind() == reflect.String:
+		return v.String()
+	case isInteger(v) && v.Kind() >= reflect.Uint:
+		return strconv.FormatUint(v.Uint(), 10)
+	case isInteger(v):
+		return strconv.FormatInt(v.Int(), 10)
+	}
+
+	return v.Interface().(fmt.Stringer).String()
+}
+
+// isBytes tells if v is a byte slice or array.
+func isBytes(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8
+}
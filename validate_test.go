@@ -0,0 +1,147 @@
+package avro
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Zip struct {
+	Code string `avro:"zip"`
+}
+
+type Contact struct {
+	Name    string           `avro:"name"`
+	Age     int64            `avro:"age"`
+	Email   *string          `avro:"email"`
+	Address Zip              `avro:"address"`
+	Color   Color            `avro:"color,enum=RED|GREEN|BLUE"`
+	Hash    [16]byte         `avro:"hash"`
+	Tags    []string         `avro:"tags"`
+	Scores  map[string]int32 `avro:"scores"`
+	Since   time.Time        `avro:"since"`
+	Phone   sql.NullString   `avro:"phone"`
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := InferSchema("avro", Contact{})
+	assert.NoError(t, err)
+
+	email := "jane@example.com"
+	valid := Contact{Name: "Jane", Email: &email, Color: "RED", Tags: []string{"a"}, Scores: map[string]int32{"a": 1}}
+	assert.NoError(t, Validate(schema, valid))
+	assert.NoError(t, Validate(schema, &valid))
+	assert.NoError(t, Validate(schema, map[string]interface{}{
+		"name": "Jane", "age": 42, "email": nil, "address": map[string]interface{}{"zip": "75001"}, "color": "BLUE",
+		"hash": make([]byte, 16), "tags": []string{}, "scores": map[string]int32{}, "since": time.Now(), "phone": "0123",
+	}))
+}
+
+// Meta is stored as a JSON column, while having its own record.
+type Meta struct {
+	A int    `avro:"a"`
+	B string `avro:"b"`
+}
+
+func (m Meta) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+type Listing struct {
+	M    Meta  `avro:"m"`
+	Prev *Meta `avro:"prev"`
+}
+
+func TestValidate_record_valuer(t *testing.T) {
+	schema, err := InferSchema("avro", Listing{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Listing","type":"record","fields":[`+
+		`{"name":"m","type":{"name":"Meta","type":"record","fields":[{"name":"a","type":"long"},{"name":"b","type":"string"}]}},`+
+		`{"name":"prev","type":["null","Meta"],"default":null}]}`, schema)
+
+	// a driver.Valuer is checked against its record rather than replaced by its value
+	doc := Listing{M: Meta{A: 1, B: "x"}, Prev: &Meta{A: 2}}
+	assert.NoError(t, Validate(schema, doc))
+
+	_, err = Marshal(schema, doc)
+	assert.NoError(t, err)
+
+	// its value is still written for the primitive types
+	assert.NoError(t, Validate(`"bytes"`, Meta{}))
+}
+
+func TestValidate_mismatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  interface{}
+		err    string
+	}{
+		{
+			"nested field",
+			`{"type":"record","name":"User","fields":[{"name":"address","type":{"type":"record","name":"Address","fields":[{"name":"zip","type":"string"}]}}]}`,
+			map[string]interface{}{"address": map[string]interface{}{"zip": 75001}},
+			"address.zip: expected string, got int",
+		},
+		{
+			"nil in a non-nullable field",
+			`{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`,
+			map[string]interface{}{"name": nil},
+			"name: expected string, got nil",
+		},
+		{
+			"out of range int",
+			`{"type":"record","name":"User","fields":[{"name":"age","type":"int"}]}`,
+			map[string]interface{}{"age": int64(1) << 40},
+			"age: 1099511627776 is out of the int range",
+		},
+		{
+			"union membership",
+			`{"type":"record","name":"User","fields":[{"name":"id","type":["null","long"]}]}`,
+			map[string]interface{}{"id": "42"},
+			"id: expected one of null, long, got string",
+		},
+		{
+			"enum symbol",
+			`{"type":"enum","name":"Color","symbols":["RED"]}`,
+			"PINK",
+			`value: "PINK" is not a symbol of Color`,
+		},
+		{
+			"fixed size",
+			`{"type":"record","name":"User","fields":[{"name":"hash","type":{"type":"fixed","name":"md5","size":16}}]}`,
+			struct {
+				Hash [8]byte `avro:"hash"`
+			}{},
+			"hash: expected 16 bytes for md5, got 8",
+		},
+		{
+			"array item",
+			`{"type":"array","items":"string"}`,
+			[]interface{}{"a", 1},
+			"[1]: expected string, got int",
+		},
+		{
+			"missing field",
+			`{"type":"record","name":"User","fields":[{"name":"name","type":"string"}]}`,
+			struct{}{},
+			"name: expected string, got nil",
+		},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.schema, tt.value)
+		if assert.Error(t, err, tt.name) {
+			assert.Equal(t, tt.err, err.Error(), tt.name)
+		}
+	}
+}
+
+func TestValidate_defaults(t *testing.T) {
+	schema := `{"type":"record","name":"User","fields":[{"name":"name","type":"string","default":"anonymous"}]}`
+	assert.NoError(t, Validate(schema, struct{}{}))
+}
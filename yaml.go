@@ -0,0 +1,183 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InferSchemaYAML infers the avro schema of the Go struct v like InferSchema, but returns it as YAML
+// for the tools consuming the schemas in that format. The structure is the same as the JSON one,
+// the keys being in the same order and indented with 2 spaces, WithIndent having no effect.
+func InferSchemaYAML(fallbackTag string, v interface{}, opts ...Option) (string, error) {
+	schema, err := InferSchemaWithOptions(v, append([]Option{WithFallbackTag(fallbackTag)}, opts...)...)
+	if err != nil {
+		return "", err
+	}
+
+	return SchemaToYAML(schema)
+}
+
+// SchemaToYAML converts the JSON avro schema to YAML, keeping the order of the keys.
+func SchemaToYAML(schema string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(schema))
+	dec.UseNumber()
+
+	node, err := decodeYAMLNode(dec)
+	if err != nil {
+		return "", fmt.Errorf("decode schema: %w", err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return "", errors.New("decode schema: unexpected data after the schema")
+	}
+
+	var b bytes.Buffer
+	writeYAML(&b, node, 0)
+
+	return b.String(), nil
+}
+
+// yamlNode is a decoded JSON value: a formatted scalar, a []yamlNode or a []yamlEntry object keeping its key order.
+type yamlNode interface{}
+
+// yamlEntry is a key of a JSON object along with its value.
+type yamlEntry struct {
+	key   string
+	value yamlNode
+}
+
+// decodeYAMLNode decodes the next JSON value of dec.
+func decodeYAMLNode(dec *json.Decoder) (yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			items := []yamlNode{}
+			for dec.More() {
+				item, err := decodeYAMLNode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				items = append(items, item)
+			}
+
+			_, err := dec.Token()
+
+			return items, err
+		}
+
+		entries := []yamlEntry{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeYAMLNode(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, yamlEntry{key: yamlString(key.(string)), value: value})
+		}
+
+		_, err := dec.Token()
+
+		return entries, err
+
+	case string:
+		return yamlString(tok), nil
+	case json.Number:
+		return tok.String(), nil
+	case bool:
+		return strconv.FormatBool(tok), nil
+	case nil:
+		return "null", nil
+	}
+
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+// yamlPlainRegexp matches the strings which can be written unquoted, such as the avro names and logical types.
+var yamlPlainRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// yamlString returns the YAML scalar of the string s, quoted unless it is plain and can't be read as another type.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return strconv.Quote(s)
+	}
+
+	if yamlPlainRegexp.MatchString(s) {
+		return s
+	}
+
+	// the escapes of Go are a subset of the ones of the YAML double-quoted scalars
+	return strconv.Quote(s)
+}
+
+// writeYAML writes the node in block style, indented by indent spaces.
+func writeYAML(b *bytes.Buffer, node yamlNode, indent int) {
+	prefix := strings.Repeat(" ", indent)
+
+	switch node := node.(type) {
+	case []yamlEntry:
+		if len(node) == 0 {
+			b.WriteString(prefix + "{}\n")
+			return
+		}
+
+		for _, e := range node {
+			if isYAMLScalar(e.value) {
+				b.WriteString(prefix + e.key + ": ")
+				writeYAML(b, e.value, 0)
+
+				continue
+			}
+
+			b.WriteString(prefix + e.key + ":\n")
+			writeYAML(b, e.value, indent+2)
+		}
+
+	case []yamlNode:
+		if len(node) == 0 {
+			b.WriteString(prefix + "[]\n")
+			return
+		}
+
+		// an item is written as a child, whose first line starts with the dash instead of its indentation
+		for _, item := range node {
+			var child bytes.Buffer
+			writeYAML(&child, item, indent+2)
+
+			b.WriteString(prefix + "- ")
+			b.Write(child.Bytes()[indent+2:])
+		}
+
+	case string:
+		b.WriteString(prefix + node + "\n")
+	}
+}
+
+// isYAMLScalar tells if the node is written on the line of its key: a scalar or an empty collection.
+func isYAMLScalar(node yamlNode) bool {
+	switch node := node.(type) {
+	case []yamlEntry:
+		return len(node) == 0
+	case []yamlNode:
+		return len(node) == 0
+	}
+
+	return true
+}
@@ -0,0 +1,77 @@
+package avro
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferSchemaYAML(t *testing.T) {
+	got, err := InferSchemaYAML("avro", Shipment{}, WithDoc("A shipment: from, to and stops"))
+	assert.NoError(t, err)
+	assert.Equal(t, `name: Shipment
+doc: "A shipment: from, to and stops"
+type: record
+fields:
+  - name: from
+    type:
+      name: Location
+      type: record
+      fields:
+        - name: street
+          type: string
+  - name: to
+    type:
+      - "null"
+      - Location
+    default: null
+  - name: stops
+    type:
+      type: map
+      values: Location
+`, got)
+}
+
+func TestSchemaToYAML(t *testing.T) {
+	got, err := SchemaToYAML(`{"type":"record","name":"R","fields":[` +
+		`{"name":"matrix","type":{"type":"array","items":{"type":"array","items":"int"}},"default":[[1,2],[]]},` +
+		`{"name":"flag","type":"boolean","default":true},` +
+		`{"name":"meta","type":{"type":"map","values":"string"},"default":{}},` +
+		`{"name":"note","type":"string","default":"yes\n\"quoted\""}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `type: record
+name: R
+fields:
+  - name: matrix
+    type:
+      type: array
+      items:
+        type: array
+        items: int
+    default:
+      - - 1
+        - 2
+      - []
+  - name: flag
+    type: boolean
+    default: true
+  - name: meta
+    type:
+      type: map
+      values: string
+    default: {}
+  - name: note
+    type: string
+    default: "yes\n\"quoted\""
+`, got)
+
+	got, err = SchemaToYAML(`"string"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "string\n", got)
+
+	_, err = SchemaToYAML(`{"type":`)
+	assert.Error(t, err)
+
+	_, err = SchemaToYAML(`"string" "int"`)
+	assert.EqualError(t, err, "decode schema: unexpected data after the schema")
+}